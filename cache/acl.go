@@ -0,0 +1,482 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	//DefaultUser is the user connections are authenticated as until they authenticate as someone else
+	DefaultUser = "default"
+	//passwordScheme prefixes password hashes so the scheme can change without breaking stored hashes
+	passwordScheme  = "pbkdf2-sha256"
+	passwordSaltLen = 16
+	passwordKeyLen  = 32
+)
+
+//passwordIterations is how many pbkdf2 iterations new password hashes use, each hash records its own so this can be
+//raised without breaking stored hashes
+var passwordIterations = 600000
+
+//Category is a set of command categories an ACL user may run
+type Category int
+
+const (
+	//ReadCategory covers commands that only read keys
+	ReadCategory Category = 1 << iota
+	//WriteCategory covers commands that change keys
+	WriteCategory
+	//AdminCategory covers commands that affect the whole cache, such as Flush and Close
+	AdminCategory
+	//AllCategories covers every command
+	AllCategories = ReadCategory | WriteCategory | AdminCategory
+)
+
+var categoryNames = map[string]Category{
+	"read":  ReadCategory,
+	"write": WriteCategory,
+	"admin": AdminCategory,
+	"all":   AllCategories,
+}
+
+//String returns the category names in c, separated by commas
+func (c Category) String() string {
+	names := make([]string, 0, len(categoryNames))
+	for name, cat := range categoryNames {
+		if cat != AllCategories && c&cat != 0 {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+//commandCategories is the categories a user needs to run each command, a user needs every one of them.  Commands
+//missing from here can't be run by anyone.
+var commandCategories = map[string]Category{
+	"get":              ReadCategory,
+	"mget":             ReadCategory,
+	"getttl":           ReadCategory,
+	"getttlinfo":       ReadCategory,
+	"exists":           ReadCategory,
+	"type":             ReadCategory,
+	"range":            ReadCategory,
+	"prefix":           ReadCategory,
+	"scan":             ReadCategory,
+	"tagged":           ReadCategory,
+	"len":              ReadCategory,
+	"set":              WriteCategory,
+	"mset":             WriteCategory,
+	"msetall":          WriteCategory,
+	"unset":            WriteCategory,
+	"munset":           WriteCategory,
+	"setttl":           WriteCategory,
+	"expireat":         WriteCategory,
+	"persist":          WriteCategory,
+	"rename":           WriteCategory,
+	"renamenx":         WriteCategory,
+	"unsetrange":       WriteCategory,
+	"unsetprefix":      WriteCategory,
+	"invalidatetag":    WriteCategory,
+	"compareanddelete": WriteCategory,
+	"getset":           ReadCategory | WriteCategory,
+	"getdel":           ReadCategory | WriteCategory,
+	"getorload":        ReadCategory | WriteCategory,
+	"copy":             ReadCategory | WriteCategory,
+	"compareandset":    ReadCategory | WriteCategory,
+	"multi":            ReadCategory | WriteCategory,
+	"exec":             ReadCategory | WriteCategory,
+	"flush":            AdminCategory,
+	"flushdb":          AdminCategory,
+	"flushall":         AdminCategory,
+	"swapdb":           AdminCategory,
+	"move":             AdminCategory,
+	"keycounts":        AdminCategory,
+	"stats":            AdminCategory,
+	"info":             AdminCategory,
+	"slowlog":          AdminCategory,
+	"slowloglen":       AdminCategory,
+	"slowlogreset":     AdminCategory,
+	"close":            AdminCategory,
+	"acl":              AdminCategory,
+}
+
+//ErrPermissionDenied is returned when a user isn't allowed to run a command or to access one of its keys
+type ErrPermissionDenied struct {
+	User    string
+	Command string
+	//Key is the key the user can't access, it's empty if the user can't run the command at all
+	Key string
+}
+
+func (e ErrPermissionDenied) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("User %v has no permissions to access key %v with %v", e.User, e.Key, e.Command)
+	}
+
+	return fmt.Sprintf("User %v has no permissions to run %v", e.User, e.Command)
+}
+
+//ErrAuthFailed is returned when a user doesn't exist, is disabled or gave the wrong password.  They all fail the same
+//way so failed logins don't reveal which users exist.
+type ErrAuthFailed string
+
+func (e ErrAuthFailed) Error() string {
+	return fmt.Sprintf("Couldn't authenticate %v, invalid username or password", string(e))
+}
+
+//ErrInvalidACL is returned when an ACL file can't be parsed
+type ErrInvalidACL struct {
+	Line int
+	Err  string
+}
+
+func (e ErrInvalidACL) Error() string {
+	return fmt.Sprintf("Invalid ACL on line %d: %v", e.Line, e.Err)
+}
+
+//User is someone who can authenticate with an ACL and what they're allowed to do once they have
+type User struct {
+	Name string
+	//Enabled users can authenticate, disabled users are kept but can't authenticate or run anything
+	Enabled bool
+	//NoPass users authenticate with any password
+	NoPass bool
+	//PasswordHash is the user's password as hashed by HashPassword, passwords are never stored in the clear
+	PasswordHash string
+	//Categories are the command categories the user can run
+	Categories Category
+	//KeyPatterns are globs, see Scan's match, for the keys the user can access.  A user without any patterns can't access
+	//any keys.
+	KeyPatterns []string
+}
+
+//HashPassword will hash password with pbkdf2 and a random salt so it can be stored in a User or an ACL file
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%v$%d$%v$%v", passwordScheme, passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+//checkPassword reports whether password hashes to hash, hashes that can't be parsed never match
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	expected, err := enc.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+//SetPassword will replace the user's password, hashing it with HashPassword
+func (u *User) SetPassword(password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	u.PasswordHash = hash
+	u.NoPass = false
+	return nil
+}
+
+//CanRun reports whether the user can run command
+func (u User) CanRun(command string) bool {
+	need, ok := commandCategories[strings.ToLower(command)]
+	return ok && u.Enabled && u.Categories&need == need
+}
+
+//CanAccess reports whether the user can access key
+func (u User) CanAccess(key string) bool {
+	for _, pattern := range u.KeyPatterns {
+		// matchGlob treats an empty pattern as matching everything but an empty pattern here is more likely a mistake
+		if pattern != "" && matchGlob(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//ACL holds the users that can authenticate with the cache and what they're allowed to do.  Users can be changed at
+//runtime with SetUser and DelUser or replaced all at once with Load.  It's safe to use concurrently.
+type ACL struct {
+	users map[string]User
+	sync.RWMutex
+}
+
+//NewACL returns an ACL holding only the default user, which can run everything on every key without a password, like a
+//Redis server that hasn't been configured.
+func NewACL() *ACL {
+	return &ACL{
+		users: map[string]User{
+			DefaultUser: {
+				Name:        DefaultUser,
+				Enabled:     true,
+				NoPass:      true,
+				Categories:  AllCategories,
+				KeyPatterns: []string{"*"},
+			},
+		},
+	}
+}
+
+//LoadACL returns an ACL holding the users in the file at path, see Load for its format
+func LoadACL(path string) (*ACL, error) {
+	a := &ACL{}
+	if err := a.LoadFile(path); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+//LoadFile will replace every user with the users in the file at path, see Load
+func (a *ACL) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	return a.Load(f)
+}
+
+//Load will replace every user with the users read from r.  The users are only replaced if all of them parse, so a bad
+//file leaves the ACL as it was.  Each user is a line like Redis' ACL files:
+//
+//	user alice on #pbkdf2-sha256$600000$... ~cache:* ~session:* +@read +@write
+//
+//on and off enable and disable the user, >password sets a password in the clear and #hash sets one hashed with
+//HashPassword, nopass lets the user authenticate with any password, ~pattern allows keys matching the pattern and
+//allkeys allows every key, and +@category and -@category allow and disallow a category of commands, one of read,
+//write, admin or all.  Blank lines and lines starting with # are ignored.  Users that aren't in the file are removed,
+//including the default user.
+func (a *ACL) Load(r io.Reader) error {
+	users := make(map[string]User)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		u, err := parseUser(strings.Fields(text))
+		if err != nil {
+			return ErrInvalidACL{Line: line, Err: err.Error()}
+		}
+
+		if _, ok := users[u.Name]; ok {
+			return ErrInvalidACL{Line: line, Err: fmt.Sprintf("user %v is defined more than once", u.Name)}
+		}
+
+		users[u.Name] = u
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	a.users = users
+	return nil
+}
+
+func parseUser(fields []string) (User, error) {
+	if len(fields) < 2 || fields[0] != "user" {
+		return User{}, fmt.Errorf("expected user <name> [rules...]")
+	}
+
+	u := User{Name: fields[1]}
+	for _, rule := range fields[2:] {
+		switch {
+		case rule == "on":
+			u.Enabled = true
+		case rule == "off":
+			u.Enabled = false
+		case rule == "nopass":
+			u.NoPass = true
+			u.PasswordHash = ""
+		case rule == "allkeys":
+			u.KeyPatterns = append(u.KeyPatterns, "*")
+		case rule == "resetkeys":
+			u.KeyPatterns = nil
+		case strings.HasPrefix(rule, ">"):
+			if err := u.SetPassword(rule[1:]); err != nil {
+				return User{}, err
+			}
+		case strings.HasPrefix(rule, "#"):
+			u.PasswordHash = rule[1:]
+			u.NoPass = false
+		case strings.HasPrefix(rule, "~"):
+			// an empty glob matches everything, which is what allkeys is for
+			if rule == "~" {
+				return User{}, fmt.Errorf("empty key pattern for user %v", u.Name)
+			}
+
+			u.KeyPatterns = append(u.KeyPatterns, rule[1:])
+		case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+			cat, ok := categoryNames[rule[2:]]
+			if !ok {
+				return User{}, fmt.Errorf("unknown category %v", rule[2:])
+			}
+
+			if rule[0] == '+' {
+				u.Categories |= cat
+			} else {
+				u.Categories &^= cat
+			}
+		default:
+			return User{}, fmt.Errorf("unknown rule %v for user %v", rule, u.Name)
+		}
+	}
+
+	return u, nil
+}
+
+//SetUser will add u, replacing any user with the same name
+func (a *ACL) SetUser(u User) {
+	u.KeyPatterns = append([]string(nil), u.KeyPatterns...)
+	a.Lock()
+	defer a.Unlock()
+	if a.users == nil {
+		a.users = make(map[string]User)
+	}
+
+	a.users[u.Name] = u
+}
+
+//DelUser will remove the user called name and reports whether they existed
+func (a *ACL) DelUser(name string) bool {
+	a.Lock()
+	defer a.Unlock()
+	_, ok := a.users[name]
+	delete(a.users, name)
+	return ok
+}
+
+//GetUser will return the user called name
+func (a *ACL) GetUser(name string) (User, bool) {
+	a.RLock()
+	defer a.RUnlock()
+	u, ok := a.users[name]
+	u.KeyPatterns = append([]string(nil), u.KeyPatterns...)
+	return u, ok
+}
+
+//Users returns the names of every user, sorted
+func (a *ACL) Users() []string {
+	a.RLock()
+	defer a.RUnlock()
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+//Authenticate checks name's password, like Redis' AUTH command.  It fails with ErrAuthFailed if the user doesn't exist,
+//is disabled or password is wrong.
+func (a *ACL) Authenticate(name, password string) error {
+	a.RLock()
+	u, ok := a.users[name]
+	a.RUnlock()
+
+	// hash the password even when the user is missing so failed logins take as long whether or not the user exists
+	if !ok || !u.Enabled {
+		checkDummyPassword(password)
+		return ErrAuthFailed(name)
+	}
+
+	if u.NoPass {
+		return nil
+	}
+
+	if !checkPassword(u.PasswordHash, password) {
+		return ErrAuthFailed(name)
+	}
+
+	return nil
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+//checkDummyPassword costs the same as checking a wrong password, it's used when a user can't authenticate at all
+func checkDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("")
+	})
+
+	checkPassword(dummyHash, password)
+}
+
+//Check will return ErrPermissionDenied unless the user called name can run command on every one of keys.  Commands
+//are named after the Cacher methods in lower case, such as get or flushdb.  Users that don't exist or are disabled
+//can't run anything.
+func (a *ACL) Check(name, command string, keys ...string) error {
+	a.RLock()
+	u, ok := a.users[name]
+	a.RUnlock()
+
+	if !ok || !u.CanRun(command) {
+		return ErrPermissionDenied{User: name, Command: command}
+	}
+
+	for _, key := range keys {
+		if !u.CanAccess(key) {
+			return ErrPermissionDenied{User: name, Command: command, Key: key}
+		}
+	}
+
+	return nil
+}
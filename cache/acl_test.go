@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//fastPasswords makes password hashing cheap for the rest of the test, each hash records its number of iterations so
+//checking them still works
+func fastPasswords(t *testing.T) {
+	iterations := passwordIterations
+	passwordIterations = 1000
+	t.Cleanup(func() {
+		passwordIterations = iterations
+	})
+}
+
+func testHash(t *testing.T, password string) string {
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("Couldn't hash password: %+v", err)
+	}
+
+	return hash
+}
+
+func TestCheckPassword(t *testing.T) {
+	fastPasswords(t)
+	hash := testHash(t, "hunter2")
+	if other := testHash(t, "hunter2"); other == hash {
+		t.Fatalf("Expected hashes of the same password to be salted differently: %v", hash)
+	}
+
+	if strings.Contains(hash, "hunter2") {
+		t.Fatalf("Password is in the clear in its hash: %v", hash)
+	}
+
+	tests := []struct {
+		hash, password string
+		expected       bool
+	}{
+		{hash, "hunter2", true},
+		{hash, "hunter3", false},
+		{hash, "", false},
+		{"", "", false},
+		{"md5$1$abc$def", "hunter2", false},
+		{strings.Replace(hash, "$1000$", "$0$", 1), "hunter2", false},
+	}
+
+	for _, test := range tests {
+		if ok := checkPassword(test.hash, test.password); ok != test.expected {
+			t.Fatalf("Expected checking %v against %v to be %v but got %v", test.password, test.hash, test.expected, ok)
+		}
+	}
+
+	// pbkdf2-hmac-sha256 with password "password", salt "salt" and 1 iteration is a well known test vector
+	key, _ := hex.DecodeString("120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b")
+	known := "pbkdf2-sha256$1$c2FsdA$" + base64.RawStdEncoding.EncodeToString(key)
+	if !checkPassword(known, "password") {
+		t.Fatalf("Known hash didn't match its password: %v", known)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	fastPasswords(t)
+	acl := NewACL()
+	acl.SetUser(User{Name: "alice", Enabled: true, PasswordHash: testHash(t, "secret")})
+	acl.SetUser(User{Name: "bob", Enabled: false, PasswordHash: testHash(t, "secret")})
+
+	tests := []struct {
+		user, password string
+		ok             bool
+	}{
+		{"alice", "secret", true},
+		{"alice", "wrong", false},
+		{"bob", "secret", false},
+		{"carol", "secret", false},
+		{DefaultUser, "anything", true},
+	}
+
+	for _, test := range tests {
+		err := acl.Authenticate(test.user, test.password)
+		if test.ok && err != nil {
+			t.Fatalf("Expected %v to authenticate but got %+v", test.user, err)
+		}
+
+		if _, failed := err.(ErrAuthFailed); !test.ok && !failed {
+			t.Fatalf("Expected %v to fail to authenticate with ErrAuthFailed but got %+v", test.user, err)
+		}
+	}
+
+	if !acl.DelUser("alice") || acl.DelUser("alice") {
+		t.Fatalf("Expected alice to be deleted exactly once")
+	}
+
+	if err := acl.Authenticate("alice", "secret"); err == nil {
+		t.Fatalf("Deleted user could still authenticate")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	acl := NewACL()
+	acl.SetUser(User{
+		Name:        "reader",
+		Enabled:     true,
+		Categories:  ReadCategory,
+		KeyPatterns: []string{"cache:*"},
+	})
+	acl.SetUser(User{
+		Name:        "writer",
+		Enabled:     true,
+		Categories:  ReadCategory | WriteCategory,
+		KeyPatterns: []string{"cache:*", "session:?"},
+	})
+
+	tests := []struct {
+		user, command string
+		keys          []string
+		ok            bool
+		deniedKey     string
+	}{
+		{"reader", "get", []string{"cache:a"}, true, ""},
+		{"reader", "GET", []string{"cache:a"}, true, ""},
+		{"reader", "get", []string{"other"}, false, "other"},
+		{"reader", "unset", []string{"cache:a"}, false, ""},
+		{"reader", "getdel", []string{"cache:a"}, false, ""},
+		{"writer", "getdel", []string{"cache:a"}, true, ""},
+		{"writer", "mget", []string{"cache:a", "session:1"}, true, ""},
+		{"writer", "mget", []string{"cache:a", "session:12"}, false, "session:12"},
+		{"writer", "multi", nil, true, ""},
+		{"reader", "multi", nil, false, ""},
+		{"writer", "flushall", nil, false, ""},
+		{"writer", "slowlogreset", nil, false, ""},
+		{DefaultUser, "slowloglen", nil, true, ""},
+		{DefaultUser, "slowlogreset", nil, true, ""},
+		{"writer", "garbage", nil, false, ""},
+		{DefaultUser, "flushall", nil, true, ""},
+		{"nobody", "get", []string{"cache:a"}, false, ""},
+	}
+
+	for _, test := range tests {
+		err := acl.Check(test.user, test.command, test.keys...)
+		if test.ok {
+			if err != nil {
+				t.Fatalf("Expected %v to be allowed to %v %v but got %+v", test.user, test.command, test.keys, err)
+			}
+			continue
+		}
+
+		denied, ok := err.(ErrPermissionDenied)
+		if !ok || denied.User != test.user || denied.Key != test.deniedKey {
+			t.Fatalf("Expected %v to be denied %v on %q but got %+v", test.user, test.command, test.deniedKey, err)
+		}
+	}
+
+	// patterns that used to take the matcher exponential time mustn't let a client's key pin a CPU
+	acl.SetUser(User{
+		Name:        "starry",
+		Enabled:     true,
+		Categories:  ReadCategory,
+		KeyPatterns: []string{strings.Repeat("*a", 7) + "*b"},
+	})
+
+	start := time.Now()
+	if err := acl.Check("starry", "get", strings.Repeat("a", 1000)); err == nil {
+		t.Fatalf("Expected a key without a b to be denied")
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("Checking a pathological key pattern took %v", took)
+	}
+
+	// permissions can change at runtime
+	u, _ := acl.GetUser("reader")
+	u.Categories |= WriteCategory
+	acl.SetUser(u)
+	if err := acl.Check("reader", "unset", "cache:a"); err != nil {
+		t.Fatalf("Expected changed permissions to apply: %+v", err)
+	}
+}
+
+func TestLoadACL(t *testing.T) {
+	fastPasswords(t)
+	hash := testHash(t, "secret")
+	file := strings.Join([]string{
+		"# the cache's users",
+		"",
+		"user alice on #" + hash + " ~cache:* +@all -@admin",
+		"user bob off >hunter2 allkeys +@read",
+		"user guest on nopass ~public:* +@read",
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "users.acl")
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatalf("Couldn't write ACL file: %+v", err)
+	}
+
+	acl, err := LoadACL(path)
+	if err != nil {
+		t.Fatalf("Couldn't load ACL file: %+v", err)
+	}
+
+	if users := strings.Join(acl.Users(), ","); users != "alice,bob,guest" {
+		t.Fatalf("Got unexpected users: %v", users)
+	}
+
+	alice, _ := acl.GetUser("alice")
+	if alice.Categories != ReadCategory|WriteCategory || alice.Categories.String() != "read,write" {
+		t.Fatalf("Got unexpected categories for alice: %v", alice.Categories)
+	}
+
+	if err := acl.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("Expected alice to authenticate with the hashed password: %+v", err)
+	}
+
+	bob, _ := acl.GetUser("bob")
+	if bob.Enabled || strings.Contains(bob.PasswordHash, "hunter2") || !checkPassword(bob.PasswordHash, "hunter2") {
+		t.Fatalf("Expected bob to be disabled with a hashed password: %+v", bob)
+	}
+
+	if err := acl.Authenticate("guest", ""); err != nil {
+		t.Fatalf("Expected guest to authenticate without a password: %+v", err)
+	}
+
+	if err := acl.Check(DefaultUser, "get", "cache:a"); err == nil {
+		t.Fatalf("Expected the default user to be removed by the file")
+	}
+
+	bad := []string{
+		"alice on",
+		"user",
+		"user alice on +@everything",
+		"user alice on sideways",
+		"user alice on ~",
+		"user alice on\nuser alice off",
+	}
+
+	for _, b := range bad {
+		err := acl.Load(strings.NewReader(b))
+		if _, ok := err.(ErrInvalidACL); !ok {
+			t.Fatalf("Expected %q to be invalid but got %+v", b, err)
+		}
+	}
+
+	if err := acl.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("Expected a bad file to leave the users alone: %+v", err)
+	}
+}
+
+func TestCommandCategories(t *testing.T) {
+	// every operation of a cache needs a category, otherwise nobody can run it
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf((*StatsCacher)(nil)).Elem(),
+		reflect.TypeOf((*OrderedCacher)(nil)).Elem(),
+		reflect.TypeOf((*io.Closer)(nil)).Elem(),
+	} {
+		for i := 0; i < typ.NumMethod(); i++ {
+			command := strings.ToLower(typ.Method(i).Name)
+			if _, ok := commandCategories[command]; !ok {
+				t.Fatalf("%v's %v has no command category", typ, typ.Method(i).Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"time"
+)
+
+//Entry is a key and value to be set by MSet or MSetAll along with the key's TTL
+type Entry struct {
+	Key   string
+	Value string
+	TTL   time.Duration
+}
+
+//MGet will retrieve several keys at once and return a Result for each key in the order they were provided.
+func (c *memCache) MGet(keys ...string) []Result {
+	defer c.observe("mget", firstKey(keys), time.Now())
+	return c.mget(keys...)
+}
+
+func (c *memCache) mget(keys ...string) []Result {
+	ks := c.keyspace()
+	results := make([]Result, 0, len(keys))
+	ks.table.Atomic(func(t HashTable) {
+		for _, key := range keys {
+			results = append(results, c.get(ks, t, key, c.loader))
+		}
+	})
+
+	return results
+}
+
+//MSet will set several keys at once, each with its own TTL, and return a Result for each entry in the order they were
+//provided.  Each entry succeeds or fails on its own, see MSetAll to set all of the entries or none of them.
+func (c *memCache) MSet(entries []Entry, opts ...SetOption) []Result {
+	defer c.observe("mset", firstEntryKey(entries), time.Now())
+	return c.mset(entries, opts...)
+}
+
+func (c *memCache) mset(entries []Entry, opts ...SetOption) []Result {
+	ks := c.keyspace()
+	so := newSetOptions(opts)
+	results := make([]Result, len(entries))
+	ks.table.Atomic(func(t HashTable) {
+		for i, e := range entries {
+			if err := so.validate(e.TTL); err != nil {
+				results[i] = Result{
+					Action: Failed,
+					Err:    err,
+				}
+				continue
+			}
+
+			results[i] = ks.set(t, e.Key, e.Value, e.TTL, so)
+		}
+	})
+
+	c.evictedResults(results)
+	return results
+}
+
+//MSetAll will set all of the entries or none of them.  If any entry is invalid every Result will be Failed, and if
+//IfAbsent or IfPresent is provided and any key doesn't meet the condition every Result will be Skipped, like Redis' MSETNX.
+func (c *memCache) MSetAll(entries []Entry, opts ...SetOption) []Result {
+	defer c.observe("msetall", firstEntryKey(entries), time.Now())
+	ks := c.keyspace()
+	so := newSetOptions(opts)
+	results := make([]Result, len(entries))
+	for _, e := range entries {
+		if err := so.validate(e.TTL); err != nil {
+			for i := range results {
+				results[i] = Result{
+					Action: Failed,
+					Err:    err,
+				}
+			}
+			return results
+		}
+	}
+
+	ks.table.Atomic(func(t HashTable) {
+		skip := false
+		if so.ifAbsent || so.ifPresent {
+			for i, e := range entries {
+				existing := t.Get(e.Key)
+				if _, missing := existing.Err.(ErrKeyNotFound); existing.Err != nil && !missing {
+					for j := range results {
+						results[j] = Result{
+							Action: Failed,
+							Err:    existing.Err,
+						}
+					}
+					return
+				}
+
+				results[i] = Result{
+					Action: Skipped,
+					n:      existing.n,
+				}
+
+				if exists := existing.Err == nil; exists == so.ifAbsent {
+					skip = true
+				}
+			}
+		}
+
+		if skip {
+			return
+		}
+
+		// every condition was checked up front, so don't check them again as keys are set
+		apply := so
+		apply.ifAbsent = false
+		apply.ifPresent = false
+		for i, e := range entries {
+			results[i] = ks.set(t, e.Key, e.Value, e.TTL, apply)
+		}
+	})
+
+	c.evictedResults(results)
+	return results
+}
+
+//MUnset will unset several keys at once and return a Result for each key in the order they were provided.
+func (c *memCache) MUnset(keys ...string) []Result {
+	defer c.observe("munset", firstKey(keys), time.Now())
+	return c.munset(keys...)
+}
+
+func (c *memCache) munset(keys ...string) []Result {
+	ks := c.keyspace()
+	results := make([]Result, 0, len(keys))
+	ks.table.Atomic(func(t HashTable) {
+		for _, key := range keys {
+			results = append(results, ks.unset(t, key))
+		}
+	})
+
+	c.evictedResults(results)
+	return results
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMSetAndMGet(t *testing.T) {
+	c := NewCache()
+	results := c.MSet([]Entry{
+		{"Test Key 1", "Test Value 1", 5 * time.Minute},
+		{"Test Key 2", "Test Value 2", 0},
+		{"Test Key 1", "Test Value 3", 0},
+	})
+
+	expected := []action{Created, Created, Updated}
+	for i, r := range results {
+		if r.Action != expected[i] || r.Err != nil {
+			t.Fatalf("Got unexpected result for entry %v: %v", i, r)
+		}
+	}
+
+	results = c.MGet("Test Key 1", "Garbage Key", "Test Key 2")
+	if results[0].GetValue() != "Test Value 3" || results[0].Action != Retrieved {
+		t.Fatalf("Got unexpected result for Test Key 1: %v", results[0])
+	}
+
+	if _, ok := results[1].Err.(ErrKeyNotFound); !ok || results[1].Action != Failed {
+		t.Fatalf("Expected ErrKeyNotFound for a garbage key: %v", results[1])
+	}
+
+	if results[2].GetValue() != "Test Value 2" {
+		t.Fatalf("Got unexpected result for Test Key 2: %v", results[2])
+	}
+
+	// the second entry for Test Key 1 didn't have a TTL so it should have cleared the first one
+	if ttl, _ := c.GetTTL("Test Key 1"); ttl != NoTTL {
+		t.Fatalf("Expected Test Key 1 to have no TTL but got %s", ttl)
+	}
+}
+
+func TestMSetAll(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 2", "Existing Value", 0)
+
+	results := c.MSetAll([]Entry{
+		{"Test Key 1", "Test Value 1", 0},
+		{"Test Key 2", "Test Value 2", 0},
+	}, IfAbsent())
+
+	for i, r := range results {
+		if r.Action != Skipped {
+			t.Fatalf("Expected every entry to be skipped when one key exists, got %v for entry %v", r, i)
+		}
+	}
+
+	if r := c.Get("Test Key 1"); r.Err == nil {
+		t.Fatalf("MSetAll set a key even though another key existed: %v", r)
+	}
+
+	results = c.MSetAll([]Entry{
+		{"Test Key 1", "Test Value 1", 1 * time.Minute},
+		{"Test Key 3", "Test Value 3", 0},
+	}, SoftTTL(2*time.Minute))
+
+	for i, r := range results {
+		if r.Action != Failed || r.Err == nil {
+			t.Fatalf("Expected every entry to fail when one is invalid, got %v for entry %v", r, i)
+		}
+	}
+
+	if r := c.Get("Test Key 1"); r.Err == nil {
+		t.Fatalf("MSetAll set a key even though another entry was invalid: %v", r)
+	}
+
+	results = c.MSetAll([]Entry{
+		{"Test Key 1", "Test Value 1", 0},
+		{"Test Key 3", "Test Value 3", 0},
+	}, IfAbsent())
+
+	for i, r := range results {
+		if r.Action != Created || r.Err != nil {
+			t.Fatalf("Expected every entry to be created, got %v for entry %v", r, i)
+		}
+	}
+}
+
+func TestMUnset(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 1", "Test Value 1", 5*time.Minute)
+	c.Set("Test Key 2", "Test Value 2", 0)
+
+	results := c.MUnset("Test Key 1", "Garbage Key", "Test Key 2")
+	if results[0].Action != Deleted || results[1].Action != Failed || results[2].Action != Deleted {
+		t.Fatalf("Got unexpected results from MUnset: %v", results)
+	}
+
+	if ttl, _ := c.GetTTL("Test Key 1"); ttl != NoKey {
+		t.Fatalf("Expected unset key to have no TTL but got %s", ttl)
+	}
+}
+
+func BenchmarkMGet(b *testing.B) {
+	c := NewCache()
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%v", i)
+		c.Set(keys[i], keys[i], 0)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.MGet(keys...)
+	}
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"sort"
+)
+
+// btreeDegree is the minimum degree of the b-tree, every node other than the root holds between btreeDegree-1 and
+// 2*btreeDegree-1 items
+const btreeDegree = 16
+
+const (
+	btreeMaxItems = 2*btreeDegree - 1
+	btreeMinItems = btreeDegree - 1
+)
+
+//btree is a b-tree of nodes ordered by key.  It isn't safe for concurrent use.
+type btree struct {
+	root  *btreeNode
+	count int
+}
+
+type btreeNode struct {
+	items    []*node
+	children []*btreeNode
+}
+
+type btreeRemove int
+
+const (
+	removeItem btreeRemove = iota
+	removeMin  btreeRemove = iota
+	removeMax  btreeRemove = iota
+)
+
+func (t *btree) get(key string) *node {
+	for n := t.root; n != nil; {
+		i, found := n.find(key)
+		if found {
+			return n.items[i]
+		}
+
+		if n.leaf() {
+			return nil
+		}
+		n = n.children[i]
+	}
+
+	return nil
+}
+
+//insert adds item to the tree, replacing and returning any item that already had the same key
+func (t *btree) insert(item *node) *node {
+	if t.root == nil {
+		t.root = &btreeNode{items: []*node{item}}
+		t.count++
+		return nil
+	}
+
+	// split a full root before walking down so there's always room to split a full child into its parent
+	if len(t.root.items) >= btreeMaxItems {
+		mid, second := t.root.split(btreeMaxItems / 2)
+		t.root = &btreeNode{
+			items:    []*node{mid},
+			children: []*btreeNode{t.root, second},
+		}
+	}
+
+	old := t.root.insert(item)
+	if old == nil {
+		t.count++
+	}
+
+	return old
+}
+
+//remove removes and returns the item with key, or nil if there isn't one
+func (t *btree) remove(key string) *node {
+	if t.root == nil {
+		return nil
+	}
+
+	out := t.root.remove(key, removeItem)
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	if out != nil {
+		t.count--
+	}
+
+	return out
+}
+
+//ascend calls fn for every item with a key greater than or equal to start, in order, until fn returns false
+func (t *btree) ascend(start string, fn func(item *node) bool) {
+	if t.root != nil {
+		t.root.ascend(start, fn)
+	}
+}
+
+func (n *btreeNode) leaf() bool {
+	return len(n.children) == 0
+}
+
+//find returns the index of the first item with a key greater than or equal to key and whether it's an exact match
+func (n *btreeNode) find(key string) (int, bool) {
+	i := sort.Search(len(n.items), func(i int) bool {
+		return n.items[i].key >= key
+	})
+
+	return i, i < len(n.items) && n.items[i].key == key
+}
+
+//split splits the node at item i, returning the item at i and a new node holding everything after it
+func (n *btreeNode) split(i int) (*node, *btreeNode) {
+	item := n.items[i]
+	next := &btreeNode{}
+	next.items = append(next.items, n.items[i+1:]...)
+	n.items = truncateItems(n.items, i)
+	if !n.leaf() {
+		next.children = append(next.children, n.children[i+1:]...)
+		n.children = truncateChildren(n.children, i+1)
+	}
+
+	return item, next
+}
+
+//maybeSplitChild splits child i if it's full and reports whether it did
+func (n *btreeNode) maybeSplitChild(i int) bool {
+	if len(n.children[i].items) < btreeMaxItems {
+		return false
+	}
+
+	item, second := n.children[i].split(btreeMaxItems / 2)
+	n.items = insertItem(n.items, i, item)
+	n.children = insertChild(n.children, i+1, second)
+	return true
+}
+
+func (n *btreeNode) insert(item *node) *node {
+	i, found := n.find(item.key)
+	if found {
+		old := n.items[i]
+		n.items[i] = item
+		return old
+	}
+
+	if n.leaf() {
+		n.items = insertItem(n.items, i, item)
+		return nil
+	}
+
+	if n.maybeSplitChild(i) {
+		switch mid := n.items[i]; {
+		case item.key > mid.key:
+			i++
+		case item.key == mid.key:
+			n.items[i] = item
+			return mid
+		}
+	}
+
+	return n.children[i].insert(item)
+}
+
+func (n *btreeNode) remove(key string, typ btreeRemove) *node {
+	var i int
+	var found bool
+	switch typ {
+	case removeMax:
+		if n.leaf() {
+			last := n.items[len(n.items)-1]
+			n.items = truncateItems(n.items, len(n.items)-1)
+			return last
+		}
+		i = len(n.items)
+	case removeMin:
+		if n.leaf() {
+			return n.removeItemAt(0)
+		}
+		i = 0
+	case removeItem:
+		i, found = n.find(key)
+		if n.leaf() {
+			if found {
+				return n.removeItemAt(i)
+			}
+			return nil
+		}
+	}
+
+	// make sure the child we're about to walk into can afford to lose an item
+	if len(n.children[i].items) <= btreeMinItems {
+		return n.growChildAndRemove(i, key, typ)
+	}
+
+	if found {
+		// swap the item out for its predecessor, which is always in a leaf
+		out := n.items[i]
+		n.items[i] = n.children[i].remove("", removeMax)
+		return out
+	}
+
+	return n.children[i].remove(key, typ)
+}
+
+//growChildAndRemove gives child i an extra item, by stealing one from a sibling or merging it with a sibling, then
+//retries the remove
+func (n *btreeNode) growChildAndRemove(i int, key string, typ btreeRemove) *node {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].items) > btreeMinItems:
+		left := n.children[i-1]
+		stolen := left.items[len(left.items)-1]
+		left.items = truncateItems(left.items, len(left.items)-1)
+		child.items = insertItem(child.items, 0, n.items[i-1])
+		n.items[i-1] = stolen
+		if !left.leaf() {
+			child.children = insertChild(child.children, 0, left.children[len(left.children)-1])
+			left.children = truncateChildren(left.children, len(left.children)-1)
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > btreeMinItems:
+		right := n.children[i+1]
+		stolen := right.removeItemAt(0)
+		child.items = append(child.items, n.items[i])
+		n.items[i] = stolen
+		if !right.leaf() {
+			child.children = append(child.children, right.removeChildAt(0))
+		}
+	default:
+		if i >= len(n.items) {
+			i--
+			child = n.children[i]
+		}
+
+		mid := n.removeItemAt(i)
+		right := n.removeChildAt(i + 1)
+		child.items = append(child.items, mid)
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+	}
+
+	return n.remove(key, typ)
+}
+
+func (n *btreeNode) ascend(start string, fn func(item *node) bool) bool {
+	i, _ := n.find(start)
+	for ; i < len(n.items); i++ {
+		if !n.leaf() && !n.children[i].ascend(start, fn) {
+			return false
+		}
+
+		if !fn(n.items[i]) {
+			return false
+		}
+	}
+
+	if !n.leaf() {
+		return n.children[len(n.children)-1].ascend(start, fn)
+	}
+
+	return true
+}
+
+func (n *btreeNode) removeItemAt(i int) *node {
+	item := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	n.items = truncateItems(n.items, len(n.items)-1)
+	return item
+}
+
+func (n *btreeNode) removeChildAt(i int) *btreeNode {
+	child := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children = truncateChildren(n.children, len(n.children)-1)
+	return child
+}
+
+func insertItem(items []*node, i int, item *node) []*node {
+	items = append(items, nil)
+	copy(items[i+1:], items[i:])
+	items[i] = item
+	return items
+}
+
+func insertChild(children []*btreeNode, i int, child *btreeNode) []*btreeNode {
+	children = append(children, nil)
+	copy(children[i+1:], children[i:])
+	children[i] = child
+	return children
+}
+
+// the truncate helpers nil out the dropped entries so the nodes they pointed to can be garbage collected
+
+func truncateItems(items []*node, n int) []*node {
+	for i := n; i < len(items); i++ {
+		items[i] = nil
+	}
+
+	return items[:n]
+}
+
+func truncateChildren(children []*btreeNode, n int) []*btreeNode {
+	for i := n; i < len(children); i++ {
+		children[i] = nil
+	}
+
+	return children[:n]
+}
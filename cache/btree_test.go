@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+//checkBTree makes sure every node is within its size limits, keys are in order and all leaves are at the same depth
+func checkBTree(t *testing.T, tree *btree) {
+	leafDepth := -1
+	count := 0
+	var walk func(n *btreeNode, depth int, lo, hi string, hasLo, hasHi bool)
+	walk = func(n *btreeNode, depth int, lo, hi string, hasLo, hasHi bool) {
+		if n != tree.root && (len(n.items) < btreeMinItems || len(n.items) > btreeMaxItems) {
+			t.Fatalf("Node has %v items, expected between %v and %v", len(n.items), btreeMinItems, btreeMaxItems)
+		}
+
+		for i, item := range n.items {
+			if (i > 0 && n.items[i-1].key >= item.key) || (hasLo && item.key <= lo) || (hasHi && item.key >= hi) {
+				t.Fatalf("Key %v is out of order", item.key)
+			}
+		}
+		count += len(n.items)
+
+		if n.leaf() {
+			if leafDepth == -1 {
+				leafDepth = depth
+			} else if leafDepth != depth {
+				t.Fatalf("Found leaves at depths %v and %v", leafDepth, depth)
+			}
+			return
+		}
+
+		if len(n.children) != len(n.items)+1 {
+			t.Fatalf("Node has %v items but %v children", len(n.items), len(n.children))
+		}
+
+		for i, child := range n.children {
+			childLo, childHasLo := lo, hasLo
+			childHi, childHasHi := hi, hasHi
+			if i > 0 {
+				childLo, childHasLo = n.items[i-1].key, true
+			}
+			if i < len(n.items) {
+				childHi, childHasHi = n.items[i].key, true
+			}
+			walk(child, depth+1, childLo, childHi, childHasLo, childHasHi)
+		}
+	}
+
+	if tree.root != nil {
+		walk(tree.root, 0, "", "", false, false)
+	}
+
+	if count != tree.count {
+		t.Fatalf("Tree has %v items but its count is %v", count, tree.count)
+	}
+}
+
+func TestBTreeRandomOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	tree := &btree{}
+	expected := make(map[string]string)
+
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("%05d", rng.Intn(3000))
+		if rng.Intn(3) == 0 {
+			removed := tree.remove(key)
+			if _, ok := expected[key]; ok != (removed != nil) {
+				t.Fatalf("Removing key %v returned %v but key existing was %v", key, removed, ok)
+			}
+			delete(expected, key)
+		} else {
+			value := fmt.Sprintf("%v", i)
+			tree.insert(&node{key: key, value: value})
+			expected[key] = value
+		}
+
+		if i%1000 == 0 {
+			checkBTree(t, tree)
+		}
+	}
+
+	checkBTree(t, tree)
+	for key, value := range expected {
+		if n := tree.get(key); n == nil || n.value != value {
+			t.Fatalf("Got unexpected node for key %v: %+v", key, n)
+		}
+	}
+
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ascended := make([]string, 0, len(keys))
+	tree.ascend("", func(n *node) bool {
+		ascended = append(ascended, n.key)
+		return true
+	})
+
+	if fmt.Sprint(ascended) != fmt.Sprint(keys) {
+		t.Fatalf("Ascending the tree didn't return every key in order")
+	}
+
+	// ascending from the middle should start at the first key at or after it and stop when asked to
+	start := keys[len(keys)/2]
+	var first string
+	tree.ascend(start, func(n *node) bool {
+		first = n.key
+		return false
+	})
+
+	if first != start {
+		t.Fatalf("Ascending from %v started at %v", start, first)
+	}
+
+	for _, key := range keys {
+		tree.remove(key)
+	}
+
+	if tree.root != nil || tree.count != 0 {
+		t.Fatalf("Expected tree to be empty after removing every key")
+	}
+}
@@ -1,44 +1,167 @@
 package cache
 
 import (
+	"context"
+	"log"
+	"sync"
 	"time"
 )
 
+const (
+	//NoTTL is returned by GetTTL for a key that exists but doesn't have a TTL, like Redis' -1
+	NoTTL time.Duration = -1
+	//NoKey is returned by GetTTL for a key that doesn't exist, like Redis' -2
+	NoKey time.Duration = -2
+)
+
 // Cacher defines the functionality a Cache needs to implement
 type Cacher interface {
-	Set(key, value string, ttl time.Duration) Result
+	Set(key, value string, ttl time.Duration, opts ...SetOption) Result
 	Unset(key string) Result
 	Get(key string) Result
 	SetTTL(key string, ttl time.Duration) Result
 	GetTTL(key string) (time.Duration, error)
+	GetTTLInfo(key string) (TTLInfo, error)
+	ExpireAt(key string, at time.Time) Result
+	Persist(key string) Result
+	GetSet(key, value string, ttl time.Duration, opts ...SetOption) Result
+	GetDel(key string) Result
+	CompareAndSet(key string, version uint64, value string, ttl time.Duration, opts ...SetOption) Result
+	CompareAndDelete(key string, version uint64) Result
+	Multi() *Tx
+	MGet(keys ...string) []Result
+	MSet(entries []Entry, opts ...SetOption) []Result
+	MSetAll(entries []Entry, opts ...SetOption) []Result
+	MUnset(keys ...string) []Result
+	Scan(cursor, match string, count int, opts ...ScanOption) (string, []string, error)
+	GetOrLoad(key string, loader Loader, ttl time.Duration, opts ...SetOption) Result
+	InvalidateTag(tag string) []Result
+	Tagged(tag string) []string
+	Len() int
+	Flush() int
+	Rename(key, newKey string) Result
+	RenameNX(key, newKey string) Result
+	Copy(key, newKey string, replace bool) Result
+	Exists(keys ...string) int
+	Type(key string) (string, error)
 }
 
 type memCache struct {
-	table       HashTable
-	ttlRegistry *ttlRegistry
+	ks         *keyspace
+	ksLock     sync.RWMutex
+	opts       options
+	evictHooks []EvictFunc
+	loads      *loadGroup
+	loader     keyLoader
+	started    time.Time
+	commands   *commandStats
+	slowlog    *slowlog
+	closeOnce  sync.Once
+}
+
+// NewCache returns a newly instantiated Cache that's ready to use.  Every Cache is also a StatsCacher and an io.Closer, and
+//caches created WithOrderedKeys are also OrderedCachers.
+func NewCache(opts ...Option) Cacher {
+	return newMemCache(newOptions(opts)).cacher()
+}
+
+func newMemCache(o options) *memCache {
+	c := &memCache{
+		opts:       o,
+		evictHooks: o.evictHooks,
+		loads:      newLoadGroup(),
+		started:    o.clock.Now(),
+		commands:   newCommandStats(),
+		slowlog:    newSlowlog(o.slowlogThreshold, o.slowlogLen),
+	}
+
+	// a nil Loader would make a non-nil keyLoader
+	if o.loader != nil {
+		c.loader = o.loader
+	}
+
+	c.ks = c.newKeyspace()
+	o.metrics.register(c)
+	return c
+}
+
+//Set will attempt to set a key and value with a specified TTL.  If TTL is less than or equal to zero it will not set the TTL
+//and will clear any TTL the key already had, unless KeepTTL is provided.
+func (c *memCache) Set(key, value string, ttl time.Duration, opts ...SetOption) Result {
+	defer c.observe("set", key, time.Now())
+	return c.set(key, value, ttl, opts...)
 }
 
-// NewCache returns a newly instantiated Cache that's ready to use
-func NewCache() Cacher {
-	table := newTable()
-	ttlReg := newTTLRegistry(table)
-	return &memCache{
-		table:       table,
-		ttlRegistry: ttlReg,
+//set is Set without observing the call, so callers that observe themselves, like SetCtx, aren't counted twice and sets
+//made on behalf of another command, like GetOrLoad's, aren't counted as set commands
+func (c *memCache) set(key, value string, ttl time.Duration, opts ...SetOption) Result {
+	ks := c.keyspace()
+	so := newSetOptions(opts)
+	if err := so.validate(ttl); err != nil {
+		return Result{
+			Action: Failed,
+			Err:    err,
+		}
 	}
+
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.set(t, key, value, ttl, so)
+	})
+
+	if r.prev != nil {
+		c.evicted(*r.prev, Replaced)
+	}
+
+	return r
 }
 
-//Set will attempt to set a key and value with a specified TTL.  If TTL is less than or equal to zero it will not set the TTL.
-func (c *memCache) Set(key, value string, ttl time.Duration) Result {
-	r := c.table.Set(key, value)
+//set sets the key and registers its TTL.  It must be called from inside of the table's Atomic.
+func (ks *keyspace) set(t HashTable, key, value string, ttl time.Duration, so setOptions) Result {
+	if so.ifAbsent || so.ifPresent {
+		existing := t.Get(key)
+		if _, missing := existing.Err.(ErrKeyNotFound); existing.Err != nil && !missing {
+			return Result{
+				Action: Failed,
+				Err:    existing.Err,
+			}
+		}
+
+		if exists := existing.Err == nil; exists == so.ifAbsent {
+			return Result{
+				Action: Skipped,
+				n:      existing.n,
+			}
+		}
+	}
+
+	r := t.Set(key, value)
 	if r.Err != nil {
 		return r
 	}
 
 	if ttl > 0 {
-		err := c.ttlRegistry.RegisterTTL(key, r.n.created, ttl)
+		var err error
+		switch {
+		case so.sliding:
+			err = ks.ttlRegistry.RegisterSlidingTTL(key, r.n.created, ttl)
+		case so.softTTL > 0:
+			err = ks.ttlRegistry.RegisterSoftTTL(key, r.n.created, so.softTTL, ttl)
+		default:
+			err = ks.ttlRegistry.RegisterTTL(key, r.n.created, ttl)
+		}
+
 		if err != nil {
-			c.table.Unset(key)
+			t.Unset(key)
+			ks.removed(r.n)
+			return Result{
+				Action: Failed,
+				Err:    err,
+			}
+		}
+	} else if !so.keepTTL {
+		err := ks.ttlRegistry.UnregisterTTL(key)
+		if _, ok := err.(ErrKeyNotFound); !ok && err != nil {
 			return Result{
 				Action: Failed,
 				Err:    err,
@@ -46,17 +169,67 @@ func (c *memCache) Set(key, value string, ttl time.Duration) Result {
 		}
 	}
 
+	ks.tags.set(key, so.tags)
+	ks.metrics.set(r.Action)
 	return r
 }
 
+//GetSet will atomically set a key and return the value it replaced.  The Result's Action will be Updated and it will hold
+//the replaced value if the key existed, or Created with an empty value if it didn't.
+func (c *memCache) GetSet(key, value string, ttl time.Duration, opts ...SetOption) Result {
+	defer c.observe("getset", key, time.Now())
+	return c.getSet(key, value, ttl, opts...)
+}
+
+func (c *memCache) getSet(key, value string, ttl time.Duration, opts ...SetOption) Result {
+	r := c.set(key, value, ttl, opts...)
+	if r.Err != nil || r.Action == Skipped {
+		return r
+	}
+
+	if r.prev == nil {
+		return Result{
+			Action: Created,
+			n: node{
+				key: key,
+			},
+		}
+	}
+
+	return Result{
+		Action: Updated,
+		n:      *r.prev,
+	}
+}
+
 //Unset will unset the provided key from the cache.
 func (c *memCache) Unset(key string) Result {
-	r := c.table.Unset(key)
+	defer c.observe("unset", key, time.Now())
+	return c.unset(key)
+}
+
+func (c *memCache) unset(key string) Result {
+	ks := c.keyspace()
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.unset(t, key)
+	})
+
+	if r.Action == Deleted {
+		c.evicted(r.n, Removed)
+	}
+
+	return r
+}
+
+//unset unsets the key and unregisters its TTL.  It must be called from inside of the table's Atomic.
+func (ks *keyspace) unset(t HashTable, key string) Result {
+	r := t.Unset(key)
 	if r.Err != nil {
 		return r
 	}
 
-	err := c.ttlRegistry.UnregisterTTL(key)
+	err := ks.ttlRegistry.UnregisterTTL(key)
 	if _, ok := err.(ErrKeyNotFound); !ok && err != nil {
 		return Result{
 			Err:    err,
@@ -64,36 +237,296 @@ func (c *memCache) Unset(key string) Result {
 		}
 	}
 
+	ks.removed(r.n)
+	ks.metrics.unset()
 	return r
 }
 
-//Get will attempt to retrieve a specified key from the cache.
+//GetDel will atomically retrieve and unset the provided key.  The Result holds the value the key had when it was unset.
+func (c *memCache) GetDel(key string) Result {
+	defer c.observe("getdel", key, time.Now())
+	return c.unset(key)
+}
+
+//Get will attempt to retrieve a specified key from the cache.  Retrieving a key with a sliding TTL resets its TTL.  If the
+//key has outlived its soft TTL the Result will be flagged as stale and the key will be refreshed in the background if the
+//cache has a Loader.
 func (c *memCache) Get(key string) Result {
-	return c.table.Get(key)
+	defer c.observe("get", key, time.Now())
+	return c.getKey(key)
+}
+
+func (c *memCache) getKey(key string) Result {
+	ks := c.keyspace()
+	return c.get(ks, ks.table, key, c.loader)
+}
+
+//get looks up key and counts whether it was found
+func (c *memCache) get(ks *keyspace, t HashTable, key string, loader keyLoader) Result {
+	r := c.lookup(ks, t, key, loader)
+	ks.metrics.get(r.Err == nil)
+	return r
+}
+
+func (c *memCache) lookup(ks *keyspace, t HashTable, key string, loader keyLoader) Result {
+	r := t.Get(key)
+	if r.Err != nil {
+		return r
+	}
+
+	ks.ttlRegistry.Touch(key, c.opts.clock.Now().UTC())
+	if stale, soft, hard := ks.ttlRegistry.Stale(key); stale {
+		r.Stale = true
+		if loader != nil {
+			c.refresh(key, r.n.version, loader, soft, hard)
+		}
+	}
+
+	return r
+}
+
+//refresh reloads a stale key in the background unless it's already being loaded.  The reloaded value is only set if the
+//key is still at version, so a key that's unset or set while it's being loaded isn't brought back or overwritten.  The
+//refresh outlives the call that found the key stale, so it's loaded without a deadline.
+func (c *memCache) refresh(key string, version uint64, loader keyLoader, soft, hard time.Duration) {
+	tags := c.keyspace().tags.of(key)
+	c.loads.doAsync(key, func() Result {
+		value, err := loader.load(context.Background(), key)
+		if err != nil {
+			log.Printf("Couldn't refresh stale key %v: %+v", key, err)
+			return Result{
+				Action: Failed,
+				Err:    ErrLoadFailed{Key: key, Err: err},
+			}
+		}
+
+		r := c.compareAndSet(key, version, value, hard, SoftTTL(soft), Tags(tags...))
+		if r.Err != nil {
+			return r
+		}
+
+		r.Action = Loaded
+		return r
+	})
 }
 
 //SetTTL will set the TTL for a provided key.
 func (c *memCache) SetTTL(key string, ttl time.Duration) Result {
-	r := c.table.Get(key)
+	defer c.observe("setttl", key, time.Now())
+	return c.setTTL(key, ttl)
+}
+
+func (c *memCache) setTTL(key string, ttl time.Duration) Result {
+	ks := c.keyspace()
+	return c.updateTTL(key, func(n node) error {
+		return ks.ttlRegistry.RegisterTTL(key, n.created, ttl)
+	})
+}
+
+//updateTTL calls register to change the TTL of key while holding the table's lock so the key can't be changed under it
+func (c *memCache) updateTTL(key string, register func(n node) error) Result {
+	ks := c.keyspace()
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.changeTTL(t, key, register)
+	})
+
+	return r
+}
+
+//changeTTL calls register to change the TTL of key if it exists.  It must be called from inside of the table's Atomic.
+func (ks *keyspace) changeTTL(t HashTable, key string, register func(n node) error) Result {
+	r := t.Get(key)
 	if r.Err != nil {
 		return r
 	}
 
-	err := c.ttlRegistry.RegisterTTL(key, r.n.created, ttl)
-	if err != nil {
+	if err := register(r.n); err != nil {
 		return Result{
 			Action: Failed,
 			Err:    err,
 		}
 	}
 
-	return Result{
-		Action: Updated,
-		n:      r.n,
-	}
+	r.Action = Updated
+	return r
 }
 
-//GetTTL will return the TTL for a provided key.
+//GetTTL will return the TTL for a provided key.  Like Redis, if the key exists but has no TTL it returns NoTTL with an
+//ErrTTLNotFound and if the key doesn't exist it returns NoKey with an ErrKeyNotFound.
 func (c *memCache) GetTTL(key string) (time.Duration, error) {
-	return c.ttlRegistry.GetTTL(key)
+	ks := c.keyspace()
+	ttl, err := ks.ttlRegistry.GetTTL(key)
+	if _, ok := err.(ErrTTLNotFound); ok {
+		return ks.missingTTL(key)
+	}
+
+	return ttl, err
+}
+
+//missingTTL works out whether a key without a TTL is missing entirely
+func (ks *keyspace) missingTTL(key string) (time.Duration, error) {
+	if r := ks.table.Get(key); r.Err != nil {
+		return NoKey, r.Err
+	}
+
+	return NoTTL, ErrTTLNotFound(key)
+}
+
+//GetOrLoad will attempt to retrieve a key from the cache and call loader to load and set it with the provided TTL if it's
+//missing.  Concurrent misses for the same key share a single call to a loader.  The Result's Action will be Retrieved if
+//the key was found in the cache and Loaded if it had to be loaded.  Keys that have outlived their soft TTL are returned
+//as stale and refreshed in the background with loader.
+func (c *memCache) GetOrLoad(key string, loader Loader, ttl time.Duration, opts ...SetOption) Result {
+	defer c.observe("getorload", key, time.Now())
+	return c.getOrLoad(key, loader, ttl, opts...)
+}
+
+func (c *memCache) getOrLoad(key string, loader Loader, ttl time.Duration, opts ...SetOption) Result {
+	ks := c.keyspace()
+	if r := c.get(ks, ks.table, key, loader); r.Err == nil || ks.closed {
+		return r
+	}
+
+	return c.load(context.Background(), ks, key, loader, ttl, opts...)
+}
+
+//load calls loader with ctx for a key that was missing from ks and sets it, unless it's negatively cached or someone
+//else loaded it first.  A loader that fails because ctx is done fails with ErrTimeout or ErrCanceled and isn't
+//negatively cached, it says nothing about the key.
+func (c *memCache) load(ctx context.Context, ks *keyspace, key string, loader keyLoader, ttl time.Duration, opts ...SetOption) Result {
+	if err := ks.negative.get(key); err != nil {
+		return Result{
+			Action: Failed,
+			Err:    err,
+		}
+	}
+
+	fn := func() Result {
+		// someone may have finished loading the key between our miss and getting here
+		if r := c.lookup(ks, ks.table, key, nil); r.Err == nil {
+			return r
+		}
+
+		value, err := loader.load(ctx, key)
+		if cerr := contextErr(ctx, key); err != nil && cerr != nil {
+			return Result{
+				Action: Failed,
+				Err:    cerr,
+			}
+		} else if err != nil {
+			err = ErrLoadFailed{Key: key, Err: err}
+			ks.negative.add(key, err)
+			return Result{
+				Action: Failed,
+				Err:    err,
+			}
+		}
+
+		r := c.set(key, value, ttl, opts...)
+		if r.Err != nil {
+			return r
+		}
+
+		r.Action = Loaded
+		return r
+	}
+
+	// we may have waited on a background refresh that found the key changed under it, or on someone whose context was
+	// done, neither of which says anything about our load
+	r := c.loads.do(key, fn)
+	for callerFailure(r.Err) && ctx.Err() == nil {
+		r = c.loads.do(key, fn)
+	}
+
+	return r
+}
+
+//callerFailure reports whether err, the Result of a shared load, only applies to the caller that started the load
+func callerFailure(err error) bool {
+	switch err.(type) {
+	case ErrVersionConflict, ErrTimeout, ErrCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+//GetTTLInfo will return a description of the TTL for a provided key, including whether it's a sliding TTL.
+func (c *memCache) GetTTLInfo(key string) (TTLInfo, error) {
+	ks := c.keyspace()
+	info, err := ks.ttlRegistry.GetTTLInfo(key)
+	if _, ok := err.(ErrTTLNotFound); ok {
+		_, err = ks.missingTTL(key)
+	}
+
+	return info, err
+}
+
+//ExpireAt will set an absolute time at which a provided key expires.  Like Redis, a time that has already passed expires
+//the key right away and the Result is Deleted.
+func (c *memCache) ExpireAt(key string, at time.Time) Result {
+	defer c.observe("expireat", key, time.Now())
+	ks := c.keyspace()
+	if at.IsZero() || at.After(c.opts.clock.Now()) {
+		return c.updateTTL(key, func(n node) error {
+			return ks.ttlRegistry.RegisterExpireAt(key, at)
+		})
+	}
+
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.unset(t, key)
+	})
+
+	if r.Action == Deleted {
+		c.evicted(r.n, Expired)
+	}
+
+	return r
+}
+
+//Persist will remove the TTL from a provided key so it never expires.
+func (c *memCache) Persist(key string) Result {
+	defer c.observe("persist", key, time.Now())
+	ks := c.keyspace()
+	return c.updateTTL(key, func(n node) error {
+		if err := ks.ttlRegistry.UnregisterTTL(key); err != nil {
+			return ErrTTLNotFound(key)
+		}
+
+		return nil
+	})
+}
+
+//evicted calls each of the registered evict hooks.  It must never be called while holding the table or registry locks.
+func (c *memCache) evicted(n node, reason EvictReason) {
+	c.opts.metrics.evicted(reason)
+	for _, hook := range c.evictHooks {
+		hook(n.key, n.value, reason)
+	}
+}
+
+//evictedResults calls the evict hooks for every value replaced or removed by results
+func (c *memCache) evictedResults(results []Result) {
+	for _, r := range results {
+		if r.prev != nil {
+			c.evicted(*r.prev, Replaced)
+		} else if r.Action == Deleted {
+			c.evicted(r.n, Removed)
+		}
+	}
+}
+
+//observe counts a call to op with key and records how long it took since start
+func (c *memCache) observe(op, key string, start time.Time) {
+	c.observeClient(op, key, c.opts.clientName, start)
+}
+
+//observeClient is observe for a call made by client
+func (c *memCache) observeClient(op, key, client string, start time.Time) {
+	took := time.Since(start)
+	c.commands.called(op)
+	c.opts.metrics.observe(op, took)
+	c.slowlog.log(op, key, client, start, took)
 }
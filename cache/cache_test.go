@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -186,3 +187,362 @@ func TestCacheGetTTL(t *testing.T) {
 		t.Fatalf("Got unexpected err or ttl for key %v: Err: %+v TTL: %s", testCases[0].Key, err, ttl)
 	}
 }
+
+func TestEvictHooks(t *testing.T) {
+	type eviction struct {
+		Key    string
+		Value  string
+		Reason EvictReason
+	}
+
+	evictions := make(chan eviction, 10)
+	var c Cacher
+	clock := NewFakeClock(time.Now())
+	c = NewCache(WithClock(clock), OnEvict(func(key, value string, reason EvictReason) {
+		// calling back into the cache would deadlock if the hook was called while holding a lock
+		c.Get(key)
+		evictions <- eviction{key, value, reason}
+	}))
+
+	expect := func(expected eviction) {
+		select {
+		case e := <-evictions:
+			if e != expected {
+				t.Fatalf("Got unexpected eviction: Actual: %+v Expected: %+v", e, expected)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Timed out waiting for eviction %+v", expected)
+		}
+	}
+
+	c.Set("Test Key 1", "Test Value 1", 0)
+	c.Set("Test Key 1", "Test Value 2", 0)
+	expect(eviction{"Test Key 1", "Test Value 1", Replaced})
+
+	c.Unset("Test Key 1")
+	expect(eviction{"Test Key 1", "Test Value 2", Removed})
+
+	c.Set("Test Key 2", "Test Value 3", 10*time.Millisecond)
+	clock.Advance(10 * time.Millisecond)
+	expect(eviction{"Test Key 2", "Test Value 3", Expired})
+
+	if r := c.Unset("Garbage Key"); r.Err == nil {
+		t.Fatalf("Expected an error unsetting a garbage key: %v", r)
+	}
+
+	select {
+	case e := <-evictions:
+		t.Fatalf("Got unexpected eviction after unsetting a garbage key: %+v", e)
+	default:
+	}
+}
+
+func TestSoftTTL(t *testing.T) {
+	refreshed := make(chan string, 10)
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock), WithLoader(func(key string) (string, error) {
+		refreshed <- key
+		return "Refreshed Value", nil
+	}))
+
+	if r := c.Set("Test Key 1", "Test Value 1", 1*time.Second, SoftTTL(1*time.Second)); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected a soft TTL that isn't shorter than the TTL to fail: %v", r)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 1", 1*time.Minute, SoftTTL(20*time.Millisecond)); r.Err != nil {
+		t.Fatalf("Couldn't set key with a soft TTL: %v", r)
+	}
+
+	if r := c.Get("Test Key 1"); r.Err != nil || r.Stale || r.GetValue() != "Test Value 1" {
+		t.Fatalf("Got unexpected result for fresh key: %v", r)
+	}
+
+	clock.Advance(30 * time.Millisecond)
+	if r := c.Get("Test Key 1"); r.Err != nil || !r.Stale || r.GetValue() != "Test Value 1" {
+		t.Fatalf("Expected stale value once the soft TTL elapsed: %v", r)
+	}
+
+	select {
+	case key := <-refreshed:
+		if key != "Test Key 1" {
+			t.Fatalf("Refreshed unexpected key %v", key)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("Timed out waiting for stale key to be refreshed")
+	}
+
+	waitForLoads(c.(*memCache))
+	if r := c.Get("Test Key 1"); r.GetValue() != "Refreshed Value" || r.Stale {
+		t.Fatalf("Expected a fresh refreshed value: %v", r)
+	}
+
+	ttl, err := c.GetTTL("Test Key 1")
+	if err != nil || ttl != 1*time.Minute {
+		t.Fatalf("Expected refresh to keep the key's hard TTL: Err: %+v TTL: %s", err, ttl)
+	}
+}
+
+func TestSoftTTLRefreshKeepsNewerWrites(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	loading := make(chan string)
+	release := make(chan bool)
+	c := NewCache(WithClock(clock), WithLoader(func(key string) (string, error) {
+		loading <- key
+		<-release
+		return "Refreshed Value", nil
+	}))
+
+	tests := []struct {
+		name   string
+		change func(key string)
+		value  string
+	}{
+		{"unset", func(key string) { c.Unset(key) }, ""},
+		{"set", func(key string) { c.Set(key, "Newer Value", 0) }, "Newer Value"},
+	}
+
+	for _, test := range tests {
+		c.Set(test.name, "Test Value", 1*time.Minute, SoftTTL(1*time.Second))
+		clock.Advance(2 * time.Second)
+		if r := c.Get(test.name); !r.Stale {
+			t.Fatalf("Expected %v to be stale: %v", test.name, r)
+		}
+
+		<-loading
+		test.change(test.name)
+		release <- true
+
+		waitForLoads(c.(*memCache))
+
+		if r := c.Get(test.name); r.GetValue() != test.value {
+			t.Fatalf("Expected the refresh not to overwrite %v of %v but got %v", test.name, test.name, r)
+		}
+	}
+
+	if calls := c.(*memCache).Stats().Commands["set"]; calls != 3 {
+		t.Fatalf("Expected background refreshes not to be counted as set commands but got %d sets", calls)
+	}
+}
+
+//waitForLoads waits for background refreshes to finish
+func waitForLoads(c *memCache) {
+	for {
+		c.loads.Lock()
+		loading := len(c.loads.calls)
+		c.loads.Unlock()
+		if loading == 0 {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlidingTTL(t *testing.T) {
+	idle := 100 * time.Millisecond
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock))
+	if r := c.Set("Test Key 1", "Test Value 1", 0, Sliding()); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected a sliding TTL without a TTL to fail: %v", r)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 1", idle, Sliding()); r.Err != nil {
+		t.Fatalf("Couldn't set key with a sliding TTL: %v", r)
+	}
+
+	info, err := c.GetTTLInfo("Test Key 1")
+	if err != nil || !info.Sliding || info.IdleTimeout != idle || info.Remaining > idle {
+		t.Fatalf("Got unexpected ttl info for sliding key: Err: %+v Info: %+v", err, info)
+	}
+
+	// keep touching the key for longer than its idle timeout
+	for i := 0; i < 12; i++ {
+		clock.Advance(idle / 4)
+		if r := c.Get("Test Key 1"); r.Err != nil {
+			t.Fatalf("Sliding key expired while it was being accessed: %v", r)
+		}
+	}
+
+	clock.Advance(2 * idle)
+	if r := c.Get("Test Key 1"); r.Err == nil {
+		t.Fatalf("Expected sliding key to expire once it went idle: %v", r)
+	}
+
+	if _, err := c.GetTTLInfo("Test Key 1"); err == nil {
+		t.Fatalf("Expected an error getting ttl info for an expired key")
+	}
+}
+
+func TestExpireAtAndPersist(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 1", "Test Value 1", 0)
+
+	at := time.Now().Add(1 * time.Hour)
+	if r := c.ExpireAt("Test Key 1", at); r.Action != Updated || r.Err != nil {
+		t.Fatalf("Couldn't set expire time for key: %v", r)
+	}
+
+	ttl, err := c.GetTTL("Test Key 1")
+	if err != nil || ttl > 1*time.Hour || ttl < 1*time.Hour-marginOfError {
+		t.Fatalf("Got unexpected err or ttl after ExpireAt: Err: %+v TTL: %s", err, ttl)
+	}
+
+	if r := c.Persist("Test Key 1"); r.Action != Updated || r.Err != nil {
+		t.Fatalf("Couldn't persist key: %v", r)
+	}
+
+	if ttl, err := c.GetTTL("Test Key 1"); ttl != NoTTL || err == nil {
+		t.Fatalf("Expected NoTTL after persisting key: Err: %+v TTL: %s", err, ttl)
+	}
+
+	if r := c.Persist("Test Key 1"); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected persisting a key without a TTL to fail: %v", r)
+	}
+
+	if r := c.ExpireAt("Garbage Key", at); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected ExpireAt on a garbage key to fail: %v", r)
+	}
+
+	// an expire time in the past expires the key right away
+	if r := c.ExpireAt("Test Key 1", time.Now().Add(-1*time.Second)); r.Action != Deleted {
+		t.Fatalf("Expected an expire time in the past to delete the key: %v", r)
+	}
+
+	if r := c.Get("Test Key 1"); r.Err == nil {
+		t.Fatalf("Key with an expire time in the past was still retrieved: %v", r)
+	}
+}
+
+func TestExpireAtNowExpires(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	evicted := make(map[string]EvictReason)
+	c := NewCache(WithClock(clock), OnEvict(func(key, value string, reason EvictReason) {
+		evicted[key] = reason
+	}))
+
+	c.Set("Test Key 1", "Test Value 1", 5*time.Minute)
+	c.Set("Test Key 2", "Test Value 2", 0)
+
+	tests := []struct {
+		key string
+		at  time.Time
+	}{
+		{"Test Key 1", clock.Now()},
+		{"Test Key 2", clock.Now().Add(-1 * time.Hour)},
+	}
+
+	for _, test := range tests {
+		if r := c.ExpireAt(test.key, test.at); r.Action != Deleted || r.GetValue() == "" {
+			t.Fatalf("Expected %v to expire right away: %v", test.key, r)
+		}
+
+		if r := c.Get(test.key); r.Err == nil {
+			t.Fatalf("Expired key %v was still retrieved: %v", test.key, r)
+		}
+
+		if evicted[test.key] != Expired {
+			t.Fatalf("Expected %v's evict hook to be called with Expired: %v", test.key, evicted)
+		}
+	}
+
+	if _, err := c.GetTTL("Test Key 1"); err == nil {
+		t.Fatalf("Expected the expired key's TTL to be unregistered")
+	}
+
+	if r := c.ExpireAt("Garbage Key", clock.Now()); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected ExpireAt in the past on a garbage key to fail: %v", r)
+	}
+}
+
+func TestSetClearsAndKeepsTTL(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 1", "Test Value 1", 5*time.Minute)
+	c.Set("Test Key 1", "Test Value 2", 0, KeepTTL())
+
+	ttl, err := c.GetTTL("Test Key 1")
+	if err != nil || ttl < 5*time.Minute-marginOfError {
+		t.Fatalf("Expected KeepTTL to keep the key's ttl: Err: %+v TTL: %s", err, ttl)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 3", 1*time.Minute, KeepTTL()); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected KeepTTL with a TTL to fail: %v", r)
+	}
+
+	c.Set("Test Key 1", "Test Value 4", 0)
+	if ttl, err := c.GetTTL("Test Key 1"); ttl != NoTTL || err == nil {
+		t.Fatalf("Expected overwriting a key without a TTL to clear its TTL: Err: %+v TTL: %s", err, ttl)
+	}
+
+	ttl, err = c.GetTTL("Garbage Key")
+	if _, ok := err.(ErrKeyNotFound); ttl != NoKey || !ok {
+		t.Fatalf("Expected NoKey and ErrKeyNotFound for a garbage key: Err: %+v TTL: %s", err, ttl)
+	}
+}
+
+func TestConditionalSet(t *testing.T) {
+	c := NewCache()
+	if r := c.Set("Test Key 1", "Test Value 1", 0, IfPresent()); r.Action != Skipped || r.Err != nil {
+		t.Fatalf("Expected IfPresent to skip a missing key: %v", r)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 1", 0, IfAbsent()); r.Action != Created || r.Err != nil {
+		t.Fatalf("Expected IfAbsent to create a missing key: %v", r)
+	}
+
+	r := c.Set("Test Key 1", "Test Value 2", 0, IfAbsent())
+	if r.Action != Skipped || r.Err != nil || r.GetValue() != "Test Value 1" {
+		t.Fatalf("Expected IfAbsent to skip an existing key: %v", r)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 3", 0, IfPresent()); r.Action != Updated || r.Err != nil {
+		t.Fatalf("Expected IfPresent to update an existing key: %v", r)
+	}
+
+	if r := c.Set("Test Key 1", "Test Value 4", 0, IfPresent(), IfAbsent()); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected IfPresent and IfAbsent together to fail: %v", r)
+	}
+
+	if r := c.Get("Test Key 1"); r.GetValue() != "Test Value 3" {
+		t.Fatalf("Got an unexpected value after conditional sets: %v", r)
+	}
+}
+
+func TestGetSetAndGetDel(t *testing.T) {
+	c := NewCache()
+	if r := c.GetSet("Test Key 1", "Test Value 1", 0); r.Action != Created || r.Err != nil || r.GetValue() != "" {
+		t.Fatalf("Got unexpected result from GetSet on a missing key: %v", r)
+	}
+
+	if r := c.GetSet("Test Key 1", "Test Value 2", 0); r.Action != Updated || r.Err != nil || r.GetValue() != "Test Value 1" {
+		t.Fatalf("Expected GetSet to return the replaced value: %v", r)
+	}
+
+	if r := c.GetDel("Test Key 1"); r.Action != Deleted || r.Err != nil || r.GetValue() != "Test Value 2" {
+		t.Fatalf("Expected GetDel to return the deleted value: %v", r)
+	}
+
+	if r := c.GetDel("Test Key 1"); r.Action != Failed || r.Err == nil {
+		t.Fatalf("Expected GetDel on a missing key to fail: %v", r)
+	}
+}
+
+func TestConcurrentIfAbsent(t *testing.T) {
+	c := NewCache()
+	results := make(chan Result, 50)
+	for i := 0; i < cap(results); i++ {
+		go func(i int) {
+			results <- c.Set("Lock", fmt.Sprintf("Owner %v", i), 0, IfAbsent())
+		}(i)
+	}
+
+	created := 0
+	for i := 0; i < cap(results); i++ {
+		if r := <-results; r.Action == Created {
+			created++
+		}
+	}
+
+	if created != 1 {
+		t.Fatalf("Expected exactly one IfAbsent set to win but %v did", created)
+	}
+}
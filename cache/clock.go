@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock tells the cache what time it is and schedules expirations.  It can be replaced with WithClock, usually with a
+//FakeClock so tests don't have to sleep for keys to expire.
+type Clock interface {
+	Now() time.Time
+	//AfterFunc calls f in its own goroutine once d has elapsed, like time.AfterFunc
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+//Timer is a call scheduled by a Clock's AfterFunc
+type Timer interface {
+	//Stop prevents the call from happening and returns false if it already happened or was already stopped
+	Stop() bool
+}
+
+//WithClock makes the cache use clock for creation times, TTLs and expiry instead of the system clock
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+//systemClock is the Clock used unless WithClock says otherwise
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+//FakeClock is a Clock that only moves when it's told to, see Advance.  Calls scheduled with AfterFunc are made
+//synchronously by Advance once the clock reaches them, so expirations have happened by the time Advance returns.
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	sync.Mutex
+}
+
+//NewFakeClock returns a FakeClock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	f     func()
+}
+
+//Now returns the fake clock's current time
+func (fc *FakeClock) Now() time.Time {
+	fc.Lock()
+	defer fc.Unlock()
+	return fc.now
+}
+
+//AfterFunc schedules f to be called once the fake clock has been advanced by d
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	fc.Lock()
+	defer fc.Unlock()
+	ft := &fakeTimer{
+		clock: fc,
+		at:    fc.now.Add(d),
+		f:     f,
+	}
+
+	fc.timers = append(fc.timers, ft)
+	return ft
+}
+
+//Stop unschedules the timer's call
+func (ft *fakeTimer) Stop() bool {
+	fc := ft.clock
+	fc.Lock()
+	defer fc.Unlock()
+	for i, t := range fc.timers {
+		if t == ft {
+			fc.timers = append(fc.timers[:i], fc.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+//Advance moves the fake clock forward by d, making every call that comes due in order.  Each call is made with the clock
+//set to the time it was scheduled for, and calls scheduled by those calls are made too if they come due in time.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.Lock()
+	until := fc.now.Add(d)
+	fc.Unlock()
+
+	for {
+		fc.Lock()
+		sort.SliceStable(fc.timers, func(i, j int) bool {
+			return fc.timers[i].at.Before(fc.timers[j].at)
+		})
+
+		if len(fc.timers) == 0 || fc.timers[0].at.After(until) {
+			fc.now = until
+			fc.Unlock()
+			return
+		}
+
+		next := fc.timers[0]
+		fc.timers = fc.timers[1:]
+		if next.at.After(fc.now) {
+			fc.now = next.at
+		}
+		fc.Unlock()
+
+		// the call is made without our lock since it's likely to schedule another
+		next.f()
+	}
+}
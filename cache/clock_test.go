@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	fired := make([]time.Time, 0)
+	record := func() {
+		fired = append(fired, clock.Now())
+	}
+
+	clock.AfterFunc(2*time.Second, record)
+	clock.AfterFunc(time.Second, func() {
+		record()
+		// calls scheduled while advancing are made if they come due in time
+		clock.AfterFunc(500*time.Millisecond, record)
+	})
+	stopped := clock.AfterFunc(time.Second, record)
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatalf("Expected only the first Stop to stop the timer")
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	expected := []time.Time{start.Add(time.Second), start.Add(1500 * time.Millisecond)}
+	if len(fired) != len(expected) {
+		t.Fatalf("Expected %v calls but got %v", len(expected), fired)
+	}
+
+	for i, at := range expected {
+		if !fired[i].Equal(at) {
+			t.Fatalf("Expected call %v to be made at %s but it was made at %s", i, at, fired[i])
+		}
+	}
+
+	clock.Advance(time.Second)
+	if len(fired) != 3 || !fired[2].Equal(start.Add(2*time.Second)) {
+		t.Fatalf("Expected the last call to be made once its time came: %v", fired)
+	}
+
+	if now := clock.Now(); !now.Equal(start.Add(2500 * time.Millisecond)) {
+		t.Fatalf("Expected the clock to be advanced to %s but it's %s", start.Add(2500*time.Millisecond), now)
+	}
+}
+
+func TestCacheWithFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock))
+	c.Set("Test Key", "Test Value", time.Minute)
+	c.Set("Sliding Key", "Test Value", 10*time.Second, Sliding())
+
+	if r := c.Get("Test Key"); !r.GetCreatedTime().Equal(clock.Now().UTC()) {
+		t.Fatalf("Expected the key to be created at the fake clock's time: %s", r.GetCreatedTime())
+	}
+
+	for i := 0; i < 10; i++ {
+		clock.Advance(5 * time.Second)
+		if r := c.Get("Sliding Key"); r.Err != nil {
+			t.Fatalf("Sliding key expired even though it was retrieved every 5 seconds: %v", r)
+		}
+	}
+
+	if ttl, err := c.GetTTL("Test Key"); err != nil || ttl != 10*time.Second {
+		t.Fatalf("Expected exactly 10 seconds left on the key but got %s %v", ttl, err)
+	}
+
+	clock.Advance(10 * time.Second)
+	if found := c.Exists("Test Key", "Sliding Key"); found != 0 {
+		t.Fatalf("Expected both keys to have expired but %v exist", found)
+	}
+}
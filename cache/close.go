@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"fmt"
+)
+
+//ErrClosed is returned by every operation on a cache after it has been closed
+type ErrClosed string
+
+func (e ErrClosed) Error() string {
+	return fmt.Sprintf("Couldn't %v, the cache is closed", string(e))
+}
+
+//Close will stop the cache's expiry timers and make every later operation fail with ErrClosed.  The keys left in the cache
+//are passed to the evict hooks as Removed so anything tied to them can be released, and the cache stops being reported by
+//its Metrics.  The cache doesn't persist anything so there's nothing to flush.  Close is safe to call concurrently and
+//only the first call closes the cache, later calls return ErrClosed.
+func (c *memCache) Close() error {
+	err := error(ErrClosed("close"))
+	c.closeOnce.Do(func() {
+		c.ksLock.Lock()
+		old := c.ks
+		c.ks = newClosedKeyspace()
+		c.ksLock.Unlock()
+
+		// operations that grabbed the old keyspace before we swapped it may still be running, so stop its timer for
+		// good rather than clearing it
+		old.ttlRegistry.Stop()
+		for _, n := range old.flush() {
+			c.evicted(n, Removed)
+		}
+
+		c.opts.metrics.unregister(c)
+		err = nil
+	})
+
+	return err
+}
+
+//newClosedKeyspace returns a keyspace whose table fails everything with ErrClosed
+func newClosedKeyspace() *keyspace {
+	table := closedTable{}
+	reg := newTTLRegistry(table, systemClock{})
+	reg.Stop()
+	return &keyspace{
+		closed:      true,
+		table:       table,
+		ttlRegistry: reg,
+		negative:    newNegativeCache(0, systemClock{}),
+		tags:        newTagIndex(),
+		watches:     newWatchIndex(),
+	}
+}
+
+//closedTable is the table of a closed cache.  It never holds any keys and fails every change.
+type closedTable struct{}
+
+func (closedTable) Set(key, value string) Result {
+	return Result{
+		Action: Failed,
+		Err:    ErrClosed("set " + key),
+	}
+}
+
+func (closedTable) Unset(key string) Result {
+	return Result{
+		Action: Failed,
+		Err:    ErrClosed("unset " + key),
+	}
+}
+
+func (closedTable) Get(key string) Result {
+	return Result{
+		Action: Failed,
+		Err:    ErrClosed("get " + key),
+	}
+}
+
+func (t closedTable) Atomic(fn func(t HashTable)) { fn(t) }
+
+func (closedTable) Scan(cursor string, count int) (string, []Result, error) {
+	return "", nil, ErrClosed("scan")
+}
+
+func (closedTable) Len() int { return 0 }
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	var evicted int32
+	var c Cacher
+	clock := NewFakeClock(time.Now())
+	c = NewCache(WithOrderedKeys(), WithClock(clock), OnEvict(func(key, value string, reason EvictReason) {
+		if reason != Removed {
+			t.Errorf("Expected %v to be evicted as removed but got %v", key, reason)
+		}
+
+		// the hooks are called without any locks held and after the cache is closed
+		if r := c.Get(key); r.Action != Failed {
+			t.Errorf("Expected the cache to be closed by the time its hooks are called: %v", r)
+		}
+
+		atomic.AddInt32(&evicted, 1)
+	}))
+
+	c.Set("Test Key", "Test Value", 50*time.Millisecond)
+	c.Set("Other Key", "Test Value", 0)
+	if err := c.(io.Closer).Close(); err != nil {
+		t.Fatalf("Couldn't close the cache: %+v", err)
+	}
+
+	if n := atomic.LoadInt32(&evicted); n != 2 {
+		t.Fatalf("Expected the remaining keys to be passed to the evict hooks but %v were", n)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&evicted); n != 2 {
+		t.Fatalf("Expected the expiry timer to be stopped but %v keys were evicted", n)
+	}
+
+	results := []Result{
+		c.Set("Test Key", "Test Value", 0),
+		c.Get("Test Key"),
+		c.Unset("Test Key"),
+		c.GetOrLoad("Test Key", func(key string) (string, error) {
+			t.Fatalf("A closed cache called its loader")
+			return "", nil
+		}, 0),
+	}
+
+	// a closed cache can't tell whether a key is present, so conditional sets fail rather than being skipped
+	results = append(results, c.Set("Test Key", "Test Value", 0, IfPresent()), c.GetSet("Test Key", "Test Value", 0, IfPresent()))
+	results = append(results, c.MSetAll([]Entry{{Key: "Test Key"}, {Key: "Other Key"}}, IfPresent())...)
+	for i, r := range results {
+		if _, ok := r.Err.(ErrClosed); !ok || r.Action != Failed {
+			t.Fatalf("Expected operation %v to fail with ErrClosed but got %v", i, r)
+		}
+	}
+
+	_, _, scanErr := c.Scan("", "", 0)
+	_, execErr := c.Multi().Exec()
+	oc := c.(OrderedCacher)
+	_, unsetErr := oc.UnsetPrefix("Test")
+	errs := []error{
+		oc.Prefix("Test", func(r Result) bool { return true }),
+		scanErr,
+		execErr,
+		unsetErr,
+	}
+
+	for i, err := range errs {
+		if _, ok := err.(ErrClosed); !ok {
+			t.Fatalf("Expected operation %v to fail with ErrClosed but got %v", i, err)
+		}
+	}
+
+	if err := c.(io.Closer).Close(); err == nil {
+		t.Fatalf("Expected closing a closed cache to fail")
+	}
+}
+
+func TestCloseConcurrently(t *testing.T) {
+	c := NewCache()
+	var wg sync.WaitGroup
+	var closed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Set("Test Key", "Test Value", time.Minute)
+			if c.(io.Closer).Close() == nil {
+				atomic.AddInt32(&closed, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if closed != 1 {
+		t.Fatalf("Expected exactly one Close to succeed but %v did", closed)
+	}
+}
+
+func TestCloseNamespaces(t *testing.T) {
+	var ns *Namespaces
+	ns = NewNamespaces(OnEvict(func(key, value string, reason EvictReason) {
+		// hooks are free to call back into the namespaces while they're closing
+		ns.Names()
+	}))
+	ns.Select("a").Set("Test Key", "Test Value", 0)
+	if err := ns.Close(); err != nil {
+		t.Fatalf("Couldn't close the namespaces: %+v", err)
+	}
+
+	if r := ns.Select("a").Get("Test Key"); r.Action != Failed {
+		t.Fatalf("Expected a closed namespace to fail: %v", r)
+	}
+
+	if r := ns.Select("b").Set("Test Key", "Test Value", 0); r.Action != Failed {
+		t.Fatalf("Expected a namespace selected after closing to be closed: %v", r)
+	}
+
+	if err := ns.SwapDB("a", "b"); err == nil {
+		t.Fatalf("Expected swapping closed namespaces to fail")
+	}
+
+	if r := ns.Move("Test Key", "a", "b"); r.Action != Failed {
+		t.Fatalf("Expected moving between closed namespaces to fail: %v", r)
+	}
+}
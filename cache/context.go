@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//ErrTimeout is returned when an operation's context passed its deadline before the operation finished
+type ErrTimeout string
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("Timed out waiting for key: %v", string(e))
+}
+
+//ErrCanceled is returned when an operation's context was canceled before the operation finished
+type ErrCanceled string
+
+func (e ErrCanceled) Error() string {
+	return fmt.Sprintf("Canceled while waiting for key: %v", string(e))
+}
+
+//ContextCacher defines the Cacher operations that take a context.  Every operation is traced with the cache's Tracer as a
+//child of any span in the context, see WithTracer, and slow operations are logged with the client in the context, see
+//ContextWithClient.  Operations fail with ErrTimeout or ErrCanceled without being applied if the context is already done
+//when they're called.  Once started they aren't interrupted, they only ever wait on the cache's locks, except for
+//GetOrLoadCtx which stops waiting on its loader when the context is done.
+type ContextCacher interface {
+	GetCtx(ctx context.Context, key string) Result
+	SetCtx(ctx context.Context, key, value string, ttl time.Duration, opts ...SetOption) Result
+	UnsetCtx(ctx context.Context, key string) Result
+	GetSetCtx(ctx context.Context, key, value string, ttl time.Duration, opts ...SetOption) Result
+	GetDelCtx(ctx context.Context, key string) Result
+	SetTTLCtx(ctx context.Context, key string, ttl time.Duration) Result
+	GetTTLCtx(ctx context.Context, key string) (time.Duration, error)
+	GetOrLoadCtx(ctx context.Context, key string, loader LoaderCtx, ttl time.Duration, opts ...SetOption) Result
+	MGetCtx(ctx context.Context, keys ...string) []Result
+	MSetCtx(ctx context.Context, entries []Entry, opts ...SetOption) []Result
+	MUnsetCtx(ctx context.Context, keys ...string) []Result
+}
+
+//NewContextCache returns a newly instantiated Cache that's ready to use through its context aware operations.  The
+//Cache also implements Cacher, and OrderedCacher if it was created WithOrderedKeys.
+func NewContextCache(opts ...Option) ContextCacher {
+	return newMemCache(newOptions(opts)).cacher().(ContextCacher)
+}
+
+//GetCtx is Get with a context, see Cacher's Get
+func (c *memCache) GetCtx(ctx context.Context, key string) Result {
+	return c.withContext(ctx, "get", key, func(context.Context) Result {
+		return c.getKey(key)
+	})
+}
+
+//SetCtx is Set with a context, see Cacher's Set
+func (c *memCache) SetCtx(ctx context.Context, key, value string, ttl time.Duration, opts ...SetOption) Result {
+	return c.withContext(ctx, "set", key, func(context.Context) Result {
+		return c.set(key, value, ttl, opts...)
+	})
+}
+
+//UnsetCtx is Unset with a context, see Cacher's Unset
+func (c *memCache) UnsetCtx(ctx context.Context, key string) Result {
+	return c.withContext(ctx, "unset", key, func(context.Context) Result {
+		return c.unset(key)
+	})
+}
+
+//GetSetCtx is GetSet with a context, see Cacher's GetSet
+func (c *memCache) GetSetCtx(ctx context.Context, key, value string, ttl time.Duration, opts ...SetOption) Result {
+	return c.withContext(ctx, "getset", key, func(context.Context) Result {
+		return c.getSet(key, value, ttl, opts...)
+	})
+}
+
+//GetDelCtx is GetDel with a context, see Cacher's GetDel
+func (c *memCache) GetDelCtx(ctx context.Context, key string) Result {
+	return c.withContext(ctx, "getdel", key, func(context.Context) Result {
+		return c.unset(key)
+	})
+}
+
+//SetTTLCtx is SetTTL with a context, see Cacher's SetTTL
+func (c *memCache) SetTTLCtx(ctx context.Context, key string, ttl time.Duration) Result {
+	return c.withContext(ctx, "setttl", key, func(context.Context) Result {
+		return c.setTTL(key, ttl)
+	})
+}
+
+//GetTTLCtx is GetTTL with a context, see Cacher's GetTTL
+func (c *memCache) GetTTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	_, span := c.startSpan(ctx, "getttl", key)
+	if err := contextErr(ctx, key); err != nil {
+		endSpanErr(span, err)
+		return 0, err
+	}
+
+	ttl, err := c.GetTTL(key)
+	endSpanErr(span, err)
+	return ttl, err
+}
+
+//GetOrLoadCtx is GetOrLoad with a context, see Cacher's GetOrLoad.  loader is called with the context and the operation's
+//span so it can give up once the context is done, and it isn't negatively cached if it does.  If the context is done
+//while waiting on loader GetOrLoadCtx returns straight away, and a loader that doesn't give up keeps running in the
+//background and its value is still set once it's loaded so the next call can use it.  Stale keys are refreshed in the
+//background without a deadline.
+func (c *memCache) GetOrLoadCtx(ctx context.Context, key string, loader LoaderCtx, ttl time.Duration, opts ...SetOption) Result {
+	return c.withContext(ctx, "getorload", key, func(ctx context.Context) Result {
+		ks := c.keyspace()
+		if r := c.get(ks, ks.table, key, loader); r.Err == nil || ks.closed {
+			return r
+		}
+
+		// only a miss waits on the loader, and a context that can never be done doesn't need to wait in the background
+		if ctx.Done() == nil {
+			return c.load(ctx, ks, key, loader, ttl, opts...)
+		}
+
+		loaded := make(chan Result, 1)
+		go func() {
+			loaded <- c.load(ctx, ks, key, loader, ttl, opts...)
+		}()
+
+		select {
+		case r := <-loaded:
+			return r
+		case <-ctx.Done():
+			return Result{
+				Action: Failed,
+				Err:    contextErr(ctx, key),
+			}
+		}
+	})
+}
+
+//MGetCtx is MGet with a context, see Cacher's MGet
+func (c *memCache) MGetCtx(ctx context.Context, keys ...string) []Result {
+	return c.withContextMulti(ctx, "mget", keys, func(context.Context) []Result {
+		return c.mget(keys...)
+	})
+}
+
+//MSetCtx is MSet with a context, see Cacher's MSet
+func (c *memCache) MSetCtx(ctx context.Context, entries []Entry, opts ...SetOption) []Result {
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+
+	return c.withContextMulti(ctx, "mset", keys, func(context.Context) []Result {
+		return c.mset(entries, opts...)
+	})
+}
+
+//MUnsetCtx is MUnset with a context, see Cacher's MUnset
+func (c *memCache) MUnsetCtx(ctx context.Context, keys ...string) []Result {
+	return c.withContextMulti(ctx, "munset", keys, func(context.Context) []Result {
+		return c.munset(keys...)
+	})
+}
+
+//withContext traces op on key and calls fn with the span's context unless ctx is already done.  fn mustn't observe itself,
+//the call is observed here so the slowlog records the context's client.
+func (c *memCache) withContext(ctx context.Context, op, key string, fn func(ctx context.Context) Result) Result {
+	ctx, span := c.startSpan(ctx, op, key)
+	var r Result
+	if err := contextErr(ctx, key); err != nil {
+		r = Result{
+			Action: Failed,
+			Err:    err,
+		}
+	} else {
+		start := time.Now()
+		r = fn(ctx)
+		c.observeClient(op, key, c.client(ctx), start)
+	}
+
+	endSpan(span, r)
+	return r
+}
+
+//withContextMulti traces op on keys and calls fn with the span's context unless ctx is already done, in which case every
+//key fails
+func (c *memCache) withContextMulti(ctx context.Context, op string, keys []string, fn func(ctx context.Context) []Result) []Result {
+	ctx, span := c.startSpan(ctx, op, firstKey(keys))
+	if err := contextErr(ctx, firstKey(keys)); err != nil {
+		results := make([]Result, 0, len(keys))
+		for _, key := range keys {
+			results = append(results, Result{
+				Action: Failed,
+				Err:    contextErr(ctx, key),
+			})
+		}
+
+		endSpanErr(span, err)
+		return results
+	}
+
+	start := time.Now()
+	results := fn(ctx)
+	c.observeClient(op, firstKey(keys), c.client(ctx), start)
+	endSpanErr(span, nil)
+	return results
+}
+
+//contextErr returns the typed error for why ctx is done, or nil if it isn't
+func contextErr(ctx context.Context, key string) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return ErrTimeout(key)
+	default:
+		return ErrCanceled(key)
+	}
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContextDone(t *testing.T) {
+	c := NewContextCache()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	if r := c.SetCtx(canceled, "Test Key", "Test Value", 0); r.Action != Failed {
+		t.Fatalf("Expected a canceled set to fail: %v", r)
+	} else if _, ok := r.Err.(ErrCanceled); !ok {
+		t.Fatalf("Expected ErrCanceled but got %T", r.Err)
+	}
+
+	if r := c.GetCtx(expired, "Test Key"); r.Action != Failed {
+		t.Fatalf("Expected a timed out get to fail: %v", r)
+	} else if _, ok := r.Err.(ErrTimeout); !ok {
+		t.Fatalf("Expected ErrTimeout but got %T", r.Err)
+	}
+
+	if _, err := c.GetTTLCtx(expired, "Test Key"); err == nil {
+		t.Fatalf("Expected a timed out GetTTL to fail")
+	}
+
+	results := c.MGetCtx(canceled, "Test Key 1", "Test Key 2")
+	for _, r := range results {
+		if _, ok := r.Err.(ErrCanceled); !ok || r.Action != Failed {
+			t.Fatalf("Expected every key of a canceled MGet to fail: %v", results)
+		}
+	}
+
+	if r := c.GetCtx(context.Background(), "Test Key"); r.Err == nil {
+		t.Fatalf("A canceled set was applied: %v", r)
+	}
+}
+
+func TestGetOrLoadCtxDeadline(t *testing.T) {
+	c := NewContextCache()
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (string, error) {
+		// a loader that ignores its context
+		<-release
+		return "Loaded Value", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r := c.GetOrLoadCtx(ctx, "Test Key", loader, 0)
+	if _, ok := r.Err.(ErrTimeout); !ok || r.Action != Failed {
+		t.Fatalf("Expected GetOrLoadCtx to time out waiting on the loader: %v", r)
+	}
+
+	// the load carries on in the background so a later call finds the value
+	close(release)
+	r = c.GetOrLoadCtx(context.Background(), "Test Key", loader, 0)
+	if r.Err != nil || r.GetValue() != "Loaded Value" {
+		t.Fatalf("Expected the background load to finish: %v", r)
+	}
+}
+
+func TestGetOrLoadCtxHit(t *testing.T) {
+	c := NewContextCache()
+	c.SetCtx(context.Background(), "Test Key", "Test Value", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loader := func(ctx context.Context, key string) (string, error) {
+		t.Fatalf("Loader was called for a cached key")
+		return "", nil
+	}
+
+	if r := c.GetOrLoadCtx(ctx, "Test Key", loader, 0); r.Action != Retrieved || r.GetValue() != "Test Value" {
+		t.Fatalf("Expected the cached value: %v", r)
+	}
+
+	// a hit shouldn't start waiting on a loader in the background, so it costs no more than a plain get
+	get := testing.AllocsPerRun(100, func() { c.GetCtx(ctx, "Test Key") })
+	getOrLoad := testing.AllocsPerRun(100, func() { c.GetOrLoadCtx(ctx, "Test Key", loader, 0) })
+	if getOrLoad > get {
+		t.Fatalf("Expected a hit to allocate no more than GetCtx's %v but got %v", get, getOrLoad)
+	}
+}
+
+func TestGetOrLoadCtxLoaderContext(t *testing.T) {
+	tracer := NewRecordingTracer()
+	c := NewContextCache(WithTracer(tracer))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	loading := make(chan context.Context, 1)
+	r := make(chan Result, 1)
+	go func() {
+		r <- c.GetOrLoadCtx(ctx, "Test Key", func(ctx context.Context, key string) (string, error) {
+			loading <- ctx
+			<-ctx.Done()
+			return "", ctx.Err()
+		}, 0)
+	}()
+
+	loaderCtx := <-loading
+	if _, ok := loaderCtx.Deadline(); !ok {
+		t.Fatalf("Expected the loader's context to have the caller's deadline")
+	}
+
+	// someone waiting on the same load mustn't fail because our context was done
+	shared := make(chan Result, 1)
+	go func() {
+		shared <- c.GetOrLoadCtx(context.Background(), "Test Key", func(ctx context.Context, key string) (string, error) {
+			return "Loaded Value", nil
+		}, 0)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if r := <-r; r.Action != Failed {
+		t.Fatalf("Expected the canceled load to fail: %v", r)
+	} else if _, ok := r.Err.(ErrCanceled); !ok {
+		t.Fatalf("Expected ErrCanceled but got %T", r.Err)
+	}
+
+	if r := <-shared; r.Err != nil || r.GetValue() != "Loaded Value" {
+		t.Fatalf("Expected the other caller to load the key itself: %v", r)
+	}
+
+	// the loader is called under the operation's span
+	spans := tracer.Spans()
+	if id, _ := loaderCtx.Value(recordedSpanKey{}).(int); id != spans[0].ID || spans[0].Name != "cache.getorload" {
+		t.Fatalf("Expected the loader to get the span of the first getorload but got span %v of %+v", id, spans)
+	}
+}
@@ -0,0 +1,402 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	//encryptMagic starts every encrypted file so they can be told apart from plaintext ones
+	encryptMagic = "YADCENC1"
+	//encryptChunkSize is how much plaintext is sealed at a time, so files don't have to fit in memory to be encrypted
+	encryptChunkSize = 64 * 1024
+	//encryptPrefixLen is the random part of each chunk's nonce, the rest is the chunk's number
+	encryptPrefixLen = 8
+	//maxKeyIDLen is the longest key ID that fits in a header
+	maxKeyIDLen = 255
+	//finalChunk flags the last chunk of a file so a truncated file can't pass as a shorter one
+	finalChunk = 1
+)
+
+//KeyProvider gives out the keys data is encrypted with.  New data is encrypted with the current key and its ID is
+//written to the header so the data can still be decrypted after the current key is rotated.
+type KeyProvider interface {
+	//CurrentKey returns the ID and key new data should be encrypted with
+	CurrentKey() (string, []byte, error)
+	//Key returns the key with id, or ErrUnknownKey if there isn't one
+	Key(id string) ([]byte, error)
+}
+
+//ErrUnknownKey is returned when data was encrypted with a key the KeyProvider doesn't have
+type ErrUnknownKey string
+
+func (e ErrUnknownKey) Error() string {
+	return fmt.Sprintf("Couldn't find encryption key %v", string(e))
+}
+
+//ErrDecrypt is returned when encrypted data can't be decrypted because it was corrupted, tampered with or truncated
+type ErrDecrypt string
+
+func (e ErrDecrypt) Error() string {
+	return fmt.Sprintf("Couldn't decrypt data: %v", string(e))
+}
+
+//ErrInvalidKeyFile is returned when a key file can't be parsed
+type ErrInvalidKeyFile struct {
+	Line int
+	Err  string
+}
+
+func (e ErrInvalidKeyFile) Error() string {
+	return fmt.Sprintf("Invalid key file on line %d: %v", e.Line, e.Err)
+}
+
+//FileKeyProvider is a KeyProvider that reads its keys from a file.  Each line of the file holds a key ID and a base64
+//encoded AES key of 16, 24 or 32 bytes separated by a space, and the last key is the current one.  Keys are rotated by
+//adding a new key to the end of the file and calling Reload, older keys should be kept until nothing is encrypted with
+//them anymore.  Blank lines and lines starting with # are ignored.
+type FileKeyProvider struct {
+	path    string
+	keys    map[string][]byte
+	current string
+	sync.RWMutex
+}
+
+//NewFileKeyProvider returns a FileKeyProvider with the keys in the file at path
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	p := &FileKeyProvider{
+		path: path,
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+//Reload will read the keys from the file again.  The keys are only replaced if all of them parse.
+func (p *FileKeyProvider) Reload() error {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	keys := make(map[string][]byte)
+	current := ""
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return ErrInvalidKeyFile{Line: line, Err: "expected <id> <base64 key>"}
+		}
+
+		id := fields[0]
+		if len(id) > maxKeyIDLen {
+			return ErrInvalidKeyFile{Line: line, Err: fmt.Sprintf("key ID is longer than %d bytes", maxKeyIDLen)}
+		}
+
+		if _, ok := keys[id]; ok {
+			return ErrInvalidKeyFile{Line: line, Err: fmt.Sprintf("key %v is defined more than once", id)}
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return ErrInvalidKeyFile{Line: line, Err: err.Error()}
+		}
+
+		if _, err := aes.NewCipher(key); err != nil {
+			return ErrInvalidKeyFile{Line: line, Err: err.Error()}
+		}
+
+		keys[id] = key
+		current = id
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if current == "" {
+		return ErrInvalidKeyFile{Line: line, Err: "no keys"}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.keys, p.current = keys, current
+	return nil
+}
+
+//CurrentKey returns the last key in the file
+func (p *FileKeyProvider) CurrentKey() (string, []byte, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.current, p.keys[p.current], nil
+}
+
+//Key returns the key with id
+func (p *FileKeyProvider) Key(id string) ([]byte, error) {
+	p.RLock()
+	defer p.RUnlock()
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey(id)
+	}
+
+	return key, nil
+}
+
+//encryptWriter seals what's written to it with AES-GCM in chunks.  The header is the magic, the length of the key ID,
+//the key ID and the random nonce prefix.  Each chunk is a flag byte, the length of the sealed chunk and the sealed
+//chunk, whose nonce is the prefix followed by the chunk's number.  The header, the chunk's number and its flag are
+//authenticated with each chunk so chunks can't be swapped between files, reordered or dropped.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	buf    []byte
+	chunk  uint32
+	err    error
+}
+
+//NewEncryptWriter returns a writer that encrypts what's written to it with keys' current key and writes it to w.  Close
+//must be called to write the last chunk, otherwise the data can't be decrypted.  It doesn't close w.
+func NewEncryptWriter(w io.Writer, keys KeyProvider) (io.WriteCloser, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(id) > maxKeyIDLen {
+		return nil, fmt.Errorf("key ID %v is longer than %d bytes", id, maxKeyIDLen)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, encryptPrefixLen)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(encryptMagic)
+	header.WriteByte(byte(len(id)))
+	header.WriteString(id)
+	header.Write(prefix)
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		header: header.Bytes(),
+		prefix: prefix,
+		buf:    make([]byte, 0, encryptChunkSize),
+	}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+//chunkNonce returns the nonce of chunk n
+func chunkNonce(prefix []byte, n uint32) []byte {
+	nonce := make([]byte, encryptPrefixLen+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptPrefixLen:], n)
+	return nonce
+}
+
+//chunkData returns the additional data authenticated with chunk n
+func chunkData(header []byte, n uint32, flag byte) []byte {
+	data := make([]byte, len(header)+5)
+	copy(data, header)
+	binary.BigEndian.PutUint32(data[len(header):], n)
+	data[len(data)-1] = flag
+	return data
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+
+		// a full buffer is only sealed once more data arrives so Close can always flag a chunk as the last
+		if len(ew.buf) == cap(ew.buf) && len(p) > 0 {
+			if ew.err = ew.seal(0); ew.err != nil {
+				return written, ew.err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (ew *encryptWriter) seal(flag byte) error {
+	if ew.chunk == ^uint32(0) {
+		return fmt.Errorf("too many chunks to encrypt")
+	}
+
+	sealed := ew.aead.Seal(nil, chunkNonce(ew.prefix, ew.chunk), ew.buf, chunkData(ew.header, ew.chunk, flag))
+	frame := make([]byte, 5, 5+len(sealed))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(sealed)))
+	if _, err := ew.w.Write(append(frame, sealed...)); err != nil {
+		return err
+	}
+
+	ew.chunk++
+	ew.buf = ew.buf[:0]
+	return nil
+}
+
+//Close will seal and write the last chunk
+func (ew *encryptWriter) Close() error {
+	if ew.err != nil {
+		return ew.err
+	}
+
+	ew.err = ew.seal(finalChunk)
+	if ew.err == nil {
+		ew.err = fmt.Errorf("the encrypt writer is closed")
+		return nil
+	}
+
+	return ew.err
+}
+
+//decryptReader opens the chunks written by an encryptWriter
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	buf    []byte
+	chunk  uint32
+	done   bool
+}
+
+//NewDecryptReader returns a reader of the plaintext of the data NewEncryptWriter wrote to r, decrypted with whichever of
+//keys' keys it was encrypted with.  Reads fail with ErrDecrypt if the data has been tampered with, truncated or added to,
+//and no plaintext is returned from a chunk that doesn't authenticate.
+func NewDecryptReader(r io.Reader, keys KeyProvider) (io.Reader, error) {
+	fixed := make([]byte, len(encryptMagic)+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, ErrDecrypt("missing header")
+	}
+
+	if string(fixed[:len(encryptMagic)]) != encryptMagic {
+		return nil, ErrDecrypt("not encrypted")
+	}
+
+	rest := make([]byte, int(fixed[len(encryptMagic)])+encryptPrefixLen)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, ErrDecrypt("missing header")
+	}
+
+	id := string(rest[:len(rest)-encryptPrefixLen])
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:      r,
+		aead:   aead,
+		header: append(fixed, rest...),
+		prefix: rest[len(rest)-encryptPrefixLen:],
+	}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+//open will read and open the next chunk
+func (dr *decryptReader) open() error {
+	frame := make([]byte, 5)
+	if _, err := io.ReadFull(dr.r, frame); err != nil {
+		return ErrDecrypt("truncated")
+	}
+
+	flag, size := frame[0], binary.BigEndian.Uint32(frame[1:])
+	if size > uint32(encryptChunkSize+dr.aead.Overhead()) {
+		return ErrDecrypt("chunk is too large")
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		return ErrDecrypt("truncated")
+	}
+
+	plain, err := dr.aead.Open(sealed[:0], chunkNonce(dr.prefix, dr.chunk), sealed, chunkData(dr.header, dr.chunk, flag))
+	if err != nil {
+		return ErrDecrypt(fmt.Sprintf("chunk %d failed authentication", dr.chunk))
+	}
+
+	// nothing may follow the last chunk, otherwise data could be appended to a file without anyone noticing
+	if flag == finalChunk {
+		if _, err := io.ReadFull(dr.r, make([]byte, 1)); err == nil {
+			return ErrDecrypt("trailing data")
+		} else if err != io.EOF {
+			return err
+		}
+	}
+
+	dr.chunk++
+	dr.buf = plain
+	dr.done = flag == finalChunk
+	return nil
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeyFile(t *testing.T, path string, ids ...string) {
+	var b bytes.Buffer
+	b.WriteString("# test keys\n")
+	for _, id := range ids {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			t.Fatalf("Couldn't generate key: %+v", err)
+		}
+
+		b.WriteString(id + " " + base64.StdEncoding.EncodeToString(key) + "\n")
+	}
+
+	// keep any keys already in the file so rotating doesn't lose them
+	old, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(old, b.Bytes()...), 0600); err != nil {
+		t.Fatalf("Couldn't write key file: %+v", err)
+	}
+}
+
+func testKeys(t *testing.T, ids ...string) (*FileKeyProvider, string) {
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeyFile(t, path, ids...)
+	keys, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("Couldn't load keys: %+v", err)
+	}
+
+	return keys, path
+}
+
+func encrypt(t *testing.T, keys KeyProvider, plain []byte) []byte {
+	var b bytes.Buffer
+	w, err := NewEncryptWriter(&b, keys)
+	if err != nil {
+		t.Fatalf("Couldn't create encrypt writer: %+v", err)
+	}
+
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Couldn't encrypt: %+v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Couldn't close encrypt writer: %+v", err)
+	}
+
+	return b.Bytes()
+}
+
+func decrypt(keys KeyProvider, data []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	keys, _ := testKeys(t, "k1")
+	sizes := []int{0, 1, encryptChunkSize - 1, encryptChunkSize, encryptChunkSize + 1, 3*encryptChunkSize + 7}
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		data := encrypt(t, keys, plain)
+		if size > 16 && bytes.Contains(data, plain[:16]) {
+			t.Fatalf("Found plaintext in %d encrypted bytes", size)
+		}
+
+		got, err := decrypt(keys, data)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("Expected %d bytes to round trip but got %d bytes %+v", size, len(got), err)
+		}
+	}
+}
+
+func TestEncryptTableContents(t *testing.T) {
+	keys, _ := testKeys(t, "k1")
+	table := newTable(systemClock{})
+	table.Set("session:1", "alice")
+	table.Set("session:2", "bob")
+
+	var b bytes.Buffer
+	w, _ := NewEncryptWriter(&b, keys)
+	enc := gob.NewEncoder(w)
+	_, results, _ := table.Scan("", rangeBatch)
+	for _, r := range results {
+		enc.Encode([2]string{r.GetKey(), r.GetValue()})
+	}
+	w.Close()
+
+	if strings.Contains(b.String(), "alice") {
+		t.Fatalf("Found a value in the clear in the encrypted table")
+	}
+
+	r, err := NewDecryptReader(&b, keys)
+	if err != nil {
+		t.Fatalf("Couldn't decrypt table: %+v", err)
+	}
+
+	restored := newTable(systemClock{})
+	dec := gob.NewDecoder(r)
+	for {
+		var kv [2]string
+		if err := dec.Decode(&kv); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Couldn't decode table: %+v", err)
+		}
+
+		restored.Set(kv[0], kv[1])
+	}
+
+	if restored.Len() != 2 || restored.Get("session:1").GetValue() != "alice" {
+		t.Fatalf("Table didn't survive encryption, got %d keys", restored.Len())
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys, path := testKeys(t, "k1")
+	old := encrypt(t, keys, []byte("old data"))
+
+	writeKeyFile(t, path, "k2")
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("Couldn't reload keys: %+v", err)
+	}
+
+	if id, _, _ := keys.CurrentKey(); id != "k2" {
+		t.Fatalf("Expected the newest key to be current but got %v", id)
+	}
+
+	current := encrypt(t, keys, []byte("new data"))
+	if !bytes.Contains(current, []byte("k2")) {
+		t.Fatalf("Expected the current key's ID in the header")
+	}
+
+	for data, expected := range map[*[]byte]string{&old: "old data", &current: "new data"} {
+		if got, err := decrypt(keys, *data); err != nil || string(got) != expected {
+			t.Fatalf("Expected %q after rotating but got %q %+v", expected, got, err)
+		}
+	}
+
+	// without the old key old data can't be read
+	others, _ := testKeys(t, "k2")
+	if _, err := decrypt(others, old); err != ErrUnknownKey("k1") {
+		t.Fatalf("Expected the old key to be unknown but got %+v", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	keys, _ := testKeys(t, "k1")
+	plain := make([]byte, 2*encryptChunkSize+10)
+	data := encrypt(t, keys, plain)
+	headerLen := len(encryptMagic) + 1 + len("k1") + encryptPrefixLen
+	chunkLen := 5 + encryptChunkSize + 16
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 1
+
+	// swap the first two chunks
+	swapped := append([]byte(nil), data[:headerLen]...)
+	swapped = append(swapped, data[headerLen+chunkLen:headerLen+2*chunkLen]...)
+	swapped = append(swapped, data[headerLen:headerLen+chunkLen]...)
+	swapped = append(swapped, data[headerLen+2*chunkLen:]...)
+
+	tests := map[string][]byte{
+		"flipped":        flipped,
+		"swapped":        swapped,
+		"truncated":      data[:headerLen+2*chunkLen],
+		"last chunk cut": data[:len(data)-1],
+		"header only":    data[:headerLen],
+		"trailing data":  append(append([]byte(nil), data...), "more"...),
+		"trailing chunk": append(append([]byte(nil), data...), data[headerLen:headerLen+chunkLen]...),
+		"not encrypted":  []byte("plain old data"),
+		"empty":          nil,
+	}
+
+	for name, test := range tests {
+		if _, err := decrypt(keys, test); err == nil {
+			t.Fatalf("Expected %v data to fail to decrypt", name)
+		} else if _, ok := err.(ErrDecrypt); !ok {
+			t.Fatalf("Expected %v data to fail with ErrDecrypt but got %+v", name, err)
+		}
+	}
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	bad := []string{
+		"",
+		"k1",
+		"k1 not-base64!",
+		"k1 " + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1 " + base64.StdEncoding.EncodeToString(make([]byte, 32)) + "\nk1 " + base64.StdEncoding.EncodeToString(make([]byte, 32)),
+	}
+
+	for _, b := range bad {
+		path := filepath.Join(t.TempDir(), "keys")
+		os.WriteFile(path, []byte(b), 0600)
+		if _, err := NewFileKeyProvider(path); err == nil {
+			t.Fatalf("Expected key file %q to be invalid", b)
+		} else if _, ok := err.(ErrInvalidKeyFile); !ok {
+			t.Fatalf("Expected key file %q to fail with ErrInvalidKeyFile but got %+v", b, err)
+		}
+	}
+
+	keys, path := testKeys(t, "k1")
+	os.WriteFile(path, []byte("garbage"), 0600)
+	if err := keys.Reload(); err == nil {
+		t.Fatalf("Expected reloading a broken key file to fail")
+	}
+
+	if _, err := keys.Key("k1"); err != nil {
+		t.Fatalf("Expected a broken key file to leave the keys alone: %+v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package cache
+
+//EvictReason describes why a key was removed from the cache
+type EvictReason int
+
+const (
+	//Expired indicates the key was removed because its TTL elapsed
+	Expired EvictReason = iota
+	//Removed indicates the key was explicitly unset
+	Removed EvictReason = iota
+	//Replaced indicates the key's previous value was overwritten by a Set
+	Replaced EvictReason = iota
+	//Evicted indicates the key was evicted to reclaim space.  Nothing produces this reason until the cache has a size limit
+	Evicted EvictReason = iota
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Removed:
+		return "removed"
+	case Replaced:
+		return "replaced"
+	case Evicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+//EvictFunc is called with the key and value that were removed from the cache along with the reason they were removed
+type EvictFunc func(key, value string, reason EvictReason)
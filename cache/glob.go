@@ -0,0 +1,112 @@
+package cache
+
+//matchGlob reports whether s matches the Redis style glob pattern.  It supports * to match any run of characters, ? to
+//match a single character, [abc], [^abc] and [a-z] character classes, and \ to escape the next character.  An empty
+//pattern matches everything.
+func matchGlob(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	return globMatch([]rune(pattern), []rune(s))
+}
+
+//globMatch matches s against p without recursion.  When a token doesn't match it backtracks to the last * and lets it
+//swallow one more character, earlier stars never need revisiting since the last one can already absorb anything they
+//could, so the work is bounded by len(p)*len(s) rather than growing exponentially with the number of stars.
+func globMatch(p, s []rune) bool {
+	pi, si := 0, 0
+	star, starS := -1, 0
+	for si < len(s) {
+		if pi < len(p) && p[pi] == '*' {
+			star, starS = pi, si
+			pi++
+			continue
+		}
+
+		if pi < len(p) {
+			if next, ok := matchToken(p, pi, s[si]); ok {
+				pi, si = next, si+1
+				continue
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+
+		// let the last * swallow one more character and try the rest of the pattern from there
+		starS++
+		pi, si = star+1, starS
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
+
+//matchToken matches c against the pattern token starting at p[pi], which isn't a *.  It returns where the next token
+//starts and whether c matched.
+func matchToken(p []rune, pi int, c rune) (int, bool) {
+	switch p[pi] {
+	case '?':
+		return pi + 1, true
+	case '[':
+		matched, rest, ok := matchClass(p[pi+1:], c)
+		if !ok {
+			// an unterminated class is matched literally
+			return pi + 1, c == '['
+		}
+
+		return len(p) - len(rest), matched
+	case '\\':
+		if pi+1 < len(p) {
+			pi++
+		}
+	}
+
+	return pi + 1, p[pi] == c
+}
+
+//matchClass matches c against the character class at the start of p, which starts just after the opening [.  It returns
+//whether c matched, the rest of the pattern after the closing ] and false if the class was never closed.
+func matchClass(p []rune, c rune) (bool, []rune, bool) {
+	negate := false
+	if len(p) > 0 && p[0] == '^' {
+		negate = true
+		p = p[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(p); i++ {
+		switch {
+		case p[i] == ']' && i > 0:
+			return matched != negate, p[i+1:], true
+		case p[i] == ']':
+			// a ] right at the start of the class is a literal
+			if c == ']' {
+				matched = true
+			}
+		case p[i] == '\\' && i+1 < len(p):
+			i++
+			if p[i] == c {
+				matched = true
+			}
+		case i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']':
+			lo, hi := p[i], p[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+		case p[i] == c:
+			matched = true
+		}
+	}
+
+	return false, nil, false
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		Pattern  string
+		Value    string
+		Expected bool
+	}{
+		{"", "anything", true},
+		{"*", "", true},
+		{"*", "tenant:1:user:9", true},
+		{"tenant:*", "tenant:1:user:9", true},
+		{"tenant:*:user:9", "tenant:1:user:9", true},
+		{"tenant:*:user:9", "tenant:1:user:10", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"h[llo", "h[llo", true},
+		{"a/b/*", "a/b/c/d", true},
+		{"**a", "bba", true},
+		{"日本*", "日本語", true},
+		{"*a*b", "xaxxb", true},
+		{"*a*b", "xaxxbc", false},
+		{"a*b*c", "abbbcbc", true},
+		{"*?", "", false},
+		{"*[ab]", "xxb", true},
+		{"h\\", "h\\", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Pattern+" "+tc.Value, func(t *testing.T) {
+			if actual := matchGlob(tc.Pattern, tc.Value); actual != tc.Expected {
+				t.Fatalf("Got unexpected match result for pattern %v and value %v: Actual: %v Expected: %v", tc.Pattern, tc.Value, actual, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestMatchGlobPathological(t *testing.T) {
+	// backtracking into every star made this take seconds, it should take microseconds
+	pattern := strings.Repeat("*a", 10) + "*b"
+	value := strings.Repeat("a", 1000)
+
+	start := time.Now()
+	if matchGlob(pattern, value) {
+		t.Fatalf("Pattern %v matched a value without a b", pattern)
+	}
+
+	if !matchGlob(pattern, value+"b") {
+		t.Fatalf("Pattern %v didn't match a value ending in b", pattern)
+	}
+
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("Matching a pathological pattern took %v", took)
+	}
+}
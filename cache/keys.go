@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+//ErrSameKey is returned when a key is copied onto itself
+type ErrSameKey string
+
+func (e ErrSameKey) Error() string {
+	return fmt.Sprintf("Source and destination keys are the same: %v", string(e))
+}
+
+//Rename will atomically rename key to newKey along with its TTL, like Redis' RENAME.  If newKey already exists it's
+//replaced.
+func (c *memCache) Rename(key, newKey string) Result {
+	defer c.observe("rename", key, time.Now())
+	return c.rename(key, newKey, true)
+}
+
+//RenameNX will atomically rename key to newKey along with its TTL only if newKey doesn't exist, like Redis' RENAMENX.
+//The Result's Action will be Skipped if newKey already exists.
+func (c *memCache) RenameNX(key, newKey string) Result {
+	defer c.observe("renamenx", key, time.Now())
+	return c.rename(key, newKey, false)
+}
+
+func (c *memCache) rename(key, newKey string, replace bool) Result {
+	ks := c.keyspace()
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.transfer(t, ks, t, key, newKey, replace, true)
+	})
+
+	if r.prev != nil {
+		c.evicted(*r.prev, Replaced)
+	}
+
+	return r
+}
+
+//Copy will atomically copy key to newKey along with its TTL, like Redis' COPY.  If newKey already exists the Result's
+//Action will be Skipped unless replace is true.
+func (c *memCache) Copy(key, newKey string, replace bool) Result {
+	defer c.observe("copy", key, time.Now())
+	if key == newKey {
+		return Result{
+			Action: Failed,
+			Err:    ErrSameKey(key),
+		}
+	}
+
+	ks := c.keyspace()
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		r = ks.transfer(t, ks, t, key, newKey, replace, false)
+	})
+
+	if r.prev != nil {
+		c.evicted(*r.prev, Replaced)
+	}
+
+	return r
+}
+
+//Exists will return how many of the provided keys exist.  Like Redis, a key provided more than once is counted more than
+//once.
+func (c *memCache) Exists(keys ...string) int {
+	defer c.observe("exists", firstKey(keys), time.Now())
+	ks := c.keyspace()
+	found := 0
+	for _, key := range keys {
+		if r := ks.table.Get(key); r.Err == nil {
+			found++
+		}
+	}
+
+	return found
+}
+
+//Type will return the type of value held by key, such as StringType.  Like Redis, if the key doesn't exist it returns
+//NoneType with an ErrKeyNotFound.
+func (c *memCache) Type(key string) (string, error) {
+	r := c.keyspace().table.Get(key)
+	if r.Err != nil {
+		return NoneType, r.Err
+	}
+
+	return r.n.valueType(), nil
+}
+
+//transfer sets newKey in dst to the value and TTL of key, removing key if remove is true.  If newKey already exists it's
+//only replaced if replace is true.  It must be called from inside of both tables' Atomic, see atomicPair.
+func (ks *keyspace) transfer(t HashTable, dst *keyspace, dt HashTable, key, newKey string, replace, remove bool) Result {
+	existing := t.Get(key)
+	if existing.Err != nil {
+		return existing
+	}
+
+	if ks == dst && key == newKey {
+		// renaming a key to itself is a no-op, unless it mustn't replace anything
+		if !replace {
+			existing.Action = Skipped
+		}
+		return existing
+	}
+
+	if !replace {
+		if r := dt.Get(newKey); r.Err == nil {
+			return Result{
+				Action: Skipped,
+				n:      existing.n,
+			}
+		}
+	}
+
+	var ti ttlInfo
+	var hasTTL bool
+	tags := ks.tags.of(key)
+	if remove {
+		t.Unset(key)
+		ti, hasTTL = ks.ttlRegistry.Detach(key)
+		ks.removed(existing.n)
+	} else {
+		ti, hasTTL = ks.ttlRegistry.Lookup(key)
+	}
+
+	r := dt.Set(newKey, existing.n.value)
+	if r.Err != nil {
+		return r
+	}
+
+	if hasTTL {
+		dst.ttlRegistry.Attach(newKey, ti)
+	} else {
+		dst.ttlRegistry.UnregisterTTL(newKey)
+	}
+
+	dst.tags.set(newKey, tags)
+	return r
+}
+
+//atomicPair calls fn while holding the locks of the tables of both src and dst, which may be the same keyspace.  Tables
+//are always locked in keyspace order, rather than name order since keyspaces can be swapped between namespaces, so two
+//calls can never deadlock each other.
+func atomicPair(src, dst *keyspace, fn func(st, dt HashTable)) {
+	if src == dst {
+		src.table.Atomic(func(t HashTable) {
+			fn(t, t)
+		})
+		return
+	}
+
+	first, second := src, dst
+	if dst.id < src.id {
+		first, second = second, first
+	}
+
+	first.table.Atomic(func(ft HashTable) {
+		second.table.Atomic(func(st HashTable) {
+			if first == src {
+				fn(ft, st)
+			} else {
+				fn(st, ft)
+			}
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRename(t *testing.T) {
+	replaced := make(map[string]EvictReason)
+	c := NewCache(OnEvict(func(key, value string, reason EvictReason) {
+		replaced[value] = reason
+	}))
+
+	c.Set("Test Key", "Test Value", 5*time.Minute, Sliding())
+	c.Set("Existing Key", "Existing Value", 0)
+
+	if r := c.Rename("Test Key", "Existing Key"); r.Action != Updated || r.GetValue() != "Test Value" {
+		t.Fatalf("Expected rename to replace the existing key: %v", r)
+	}
+
+	if replaced["Existing Value"] != Replaced {
+		t.Fatalf("Expected the overwritten value to be reported as replaced: %v", replaced)
+	}
+
+	if c.Exists("Test Key") != 0 {
+		t.Fatalf("Renamed key still exists")
+	}
+
+	info, err := c.GetTTLInfo("Existing Key")
+	if err != nil || !info.Sliding || info.IdleTimeout != 5*time.Minute {
+		t.Fatalf("Expected the sliding TTL to be renamed with the key: %v %v", info, err)
+	}
+
+	if _, err := c.GetTTL("Test Key"); err == nil {
+		t.Fatalf("Expected the TTL to be removed from the old key")
+	}
+
+	if r := c.Rename("Garbage Key", "Other Key"); r.Action != Failed {
+		t.Fatalf("Expected renaming a missing key to fail: %v", r)
+	}
+
+	if r := c.Rename("Existing Key", "Existing Key"); r.Err != nil || c.Exists("Existing Key") != 1 {
+		t.Fatalf("Expected renaming a key to itself to do nothing: %v", r)
+	}
+}
+
+func TestRenameNX(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key", "Test Value", 0)
+	c.Set("Existing Key", "Existing Value", 5*time.Minute)
+
+	tests := []struct {
+		key, newKey string
+		expected    action
+	}{
+		{"Test Key", "Existing Key", Skipped},
+		{"Test Key", "Test Key", Skipped},
+		{"Test Key", "New Key", Created},
+	}
+
+	for _, test := range tests {
+		if r := c.RenameNX(test.key, test.newKey); r.Action != test.expected {
+			t.Fatalf("Expected renaming %v to %v to be %v but got %v", test.key, test.newKey, test.expected, r)
+		}
+	}
+
+	if r := c.Get("Existing Key"); r.GetValue() != "Existing Value" {
+		t.Fatalf("RenameNX overwrote an existing key: %v", r)
+	}
+
+	if ttl, _ := c.GetTTL("New Key"); ttl != NoTTL {
+		t.Fatalf("Expected the renamed key to have no TTL but got %s", ttl)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock))
+	c.Set("Test Key", "Test Value", 50*time.Millisecond)
+	c.Set("Existing Key", "Existing Value", 5*time.Minute)
+
+	if r := c.Copy("Test Key", "Existing Key", false); r.Action != Skipped {
+		t.Fatalf("Expected copying onto an existing key to be skipped: %v", r)
+	}
+
+	if r := c.Copy("Test Key", "Test Key", true); r.Action != Failed {
+		t.Fatalf("Expected copying a key onto itself to fail: %v", r)
+	}
+
+	if r := c.Copy("Test Key", "Existing Key", true); r.Action != Updated {
+		t.Fatalf("Expected copying with replace to update the existing key: %v", r)
+	}
+
+	if r := c.MGet("Test Key", "Existing Key"); r[0].GetValue() != "Test Value" || r[1].GetValue() != "Test Value" {
+		t.Fatalf("Expected both keys to hold the copied value: %v", r)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if found := c.Exists("Test Key", "Existing Key"); found != 0 {
+		t.Fatalf("Expected the copy to expire with the original's TTL but %v keys exist", found)
+	}
+}
+
+func TestCopyAcrossNamespaces(t *testing.T) {
+	ns := NewNamespaces()
+	ns.Select("a").Set("Test Key", "Test Value", 5*time.Minute)
+
+	if r := ns.Copy("a", "Test Key", "b", "Copied Key", false); r.Action != Created {
+		t.Fatalf("Expected the key to be copied to b: %v", r)
+	}
+
+	if r := ns.Select("b").Get("Copied Key"); r.GetValue() != "Test Value" {
+		t.Fatalf("Got unexpected value for the copied key: %v", r)
+	}
+
+	if ttl, err := ns.Select("b").GetTTL("Copied Key"); err != nil || ttl <= 0 {
+		t.Fatalf("Expected the TTL to be copied: %s %v", ttl, err)
+	}
+
+	if ns.Select("a").Exists("Test Key") != 1 {
+		t.Fatalf("Copy removed the original key")
+	}
+}
+
+func TestExistsAndType(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key", "Test Value", 0)
+
+	if found := c.Exists("Test Key", "Garbage Key", "Test Key"); found != 2 {
+		t.Fatalf("Expected keys provided twice to be counted twice, got %v", found)
+	}
+
+	if typ, err := c.Type("Test Key"); typ != StringType || err != nil {
+		t.Fatalf("Expected %v but got %v %v", StringType, typ, err)
+	}
+
+	if typ, err := c.Type("Garbage Key"); typ != NoneType || err == nil {
+		t.Fatalf("Expected %v but got %v %v", NoneType, typ, err)
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// lastKeyspaceID gives every keyspace a unique id, which is used to always lock several keyspaces in the same order
+var lastKeyspaceID uint64
+
+//keyspace holds a cache's keys along with their TTLs.  A cache's keyspace can be swapped out from under it, so
+//operations should grab the keyspace once and use it for the whole operation.
+type keyspace struct {
+	id          uint64
+	closed      bool
+	table       HashTable
+	ttlRegistry *ttlRegistry
+	negative    *negativeCache
+	tags        *tagIndex
+	watches     *watchIndex
+	metrics     *Metrics
+}
+
+func (c *memCache) newKeyspace() *keyspace {
+	table := c.opts.newTable(c.opts.clock)
+	ttlReg := newTTLRegistry(table, c.opts.clock)
+	ttlReg.onExpire = func(n node) {
+		c.evicted(n, Expired)
+	}
+
+	ks := &keyspace{
+		id:          atomic.AddUint64(&lastKeyspaceID, 1),
+		table:       table,
+		ttlRegistry: ttlReg,
+		negative:    newNegativeCache(c.opts.negativeTTL, c.opts.clock),
+		tags:        newTagIndex(),
+		watches:     newWatchIndex(),
+		metrics:     c.opts.metrics,
+	}
+
+	ttlReg.onUnset = ks.removed
+	return ks
+}
+
+//removed cleans up after a node that was removed from the table.  It must be called from inside of the table's Atomic.
+func (ks *keyspace) removed(n node) {
+	ks.tags.remove(n.key)
+	ks.watches.removed(n)
+}
+
+//keyspace returns the cache's current keyspace
+func (c *memCache) keyspace() *keyspace {
+	c.ksLock.RLock()
+	defer c.ksLock.RUnlock()
+	return c.ks
+}
+
+//flush unsets every key and TTL in the keyspace and returns the removed keys
+func (ks *keyspace) flush() []node {
+	removed := make([]node, 0)
+	ks.table.Atomic(func(t HashTable) {
+		keys := make([]string, 0, t.Len())
+		cursor := ""
+		for {
+			next, results, err := t.Scan(cursor, t.Len()+1)
+			if err != nil {
+				log.Printf("Couldn't scan keys while flushing: %+v", err)
+				break
+			}
+
+			for _, r := range results {
+				keys = append(keys, r.n.key)
+			}
+
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		for _, key := range keys {
+			if r := t.Unset(key); r.Err == nil {
+				ks.watches.removed(r.n)
+				removed = append(removed, r.n)
+			}
+		}
+
+		ks.ttlRegistry.Clear()
+		ks.tags.clear()
+	})
+
+	return removed
+}
+
+//Len will return the number of keys in the cache
+func (c *memCache) Len() int {
+	return c.keyspace().table.Len()
+}
+
+//Flush will unset every key in the cache, like Redis' FLUSHDB, and returns how many keys were removed.
+func (c *memCache) Flush() int {
+	defer c.observe("flush", "", time.Now())
+	ks := c.keyspace()
+	if ks.closed {
+		return 0
+	}
+
+	removed := ks.flush()
+	for _, n := range removed {
+		c.evicted(n, Removed)
+	}
+
+	return len(removed)
+}
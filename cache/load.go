@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNegativeTTL = 1 * time.Second
+	minNegativeSweep   = 64
+)
+
+//Loader loads the value for a key that could not be found in the cache
+type Loader func(key string) (string, error)
+
+//LoaderCtx loads the value for a key that could not be found in the cache and should give up once ctx is done.  ctx is
+//the context of the operation that needs the key, along with the operation's span, see WithTracer.
+type LoaderCtx func(ctx context.Context, key string) (string, error)
+
+//keyLoader is a Loader or a LoaderCtx, so either can be passed down to the loads without wrapping one in the other
+type keyLoader interface {
+	load(ctx context.Context, key string) (string, error)
+}
+
+func (l Loader) load(ctx context.Context, key string) (string, error) {
+	return l(key)
+}
+
+func (l LoaderCtx) load(ctx context.Context, key string) (string, error) {
+	return l(ctx, key)
+}
+
+//ErrLoadFailed is returned when a Loader failed to load a key.  The failure is cached for a short time so repeated
+//misses for the same key don't hammer the backing store.
+type ErrLoadFailed struct {
+	Key string
+	Err error
+}
+
+func (e ErrLoadFailed) Error() string {
+	return fmt.Sprintf("Couldn't load key %v: %v", e.Key, e.Err)
+}
+
+type loadCall struct {
+	wg sync.WaitGroup
+	r  Result
+}
+
+//loadGroup makes sure only one load is in flight for a key at a time.  Callers that ask for a key that's already being
+//loaded wait for and share the result of the original call.
+type loadGroup struct {
+	calls map[string]*loadCall
+	sync.Mutex
+}
+
+func newLoadGroup() *loadGroup {
+	return &loadGroup{
+		calls: make(map[string]*loadCall),
+	}
+}
+
+func (g *loadGroup) do(key string, fn func() Result) Result {
+	call, started := g.start(key)
+	if !started {
+		call.wg.Wait()
+		return call.r
+	}
+
+	defer g.finish(key, call)
+	call.r = fn()
+	return call.r
+}
+
+//doAsync calls fn in the background unless a call for key is already in flight
+func (g *loadGroup) doAsync(key string, fn func() Result) {
+	call, started := g.start(key)
+	if !started {
+		return
+	}
+
+	go func() {
+		defer g.finish(key, call)
+		call.r = fn()
+	}()
+}
+
+func (g *loadGroup) start(key string) (*loadCall, bool) {
+	g.Lock()
+	defer g.Unlock()
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+
+	call := &loadCall{
+		r: Result{
+			Action: Failed,
+			Err:    ErrLoadFailed{Key: key, Err: fmt.Errorf("loader did not return")},
+		},
+	}
+	call.wg.Add(1)
+	g.calls[key] = call
+	return call, true
+}
+
+func (g *loadGroup) finish(key string, call *loadCall) {
+	g.Lock()
+	delete(g.calls, key)
+	g.Unlock()
+	call.wg.Done()
+}
+
+type negativeEntry struct {
+	err    error
+	expire time.Time
+}
+
+//negativeCache remembers loader failures for a short time
+type negativeCache struct {
+	ttl     time.Duration
+	entries map[string]negativeEntry
+	sweepAt int
+	clock   Clock
+	sync.Mutex
+}
+
+func newNegativeCache(ttl time.Duration, clock Clock) *negativeCache {
+	return &negativeCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]negativeEntry),
+		sweepAt: minNegativeSweep,
+	}
+}
+
+func (nc *negativeCache) get(key string) error {
+	nc.Lock()
+	defer nc.Unlock()
+	e, ok := nc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if !e.expire.After(nc.clock.Now().UTC()) {
+		delete(nc.entries, key)
+		return nil
+	}
+
+	return e.err
+}
+
+func (nc *negativeCache) add(key string, err error) {
+	if nc.ttl <= 0 {
+		return
+	}
+
+	nc.Lock()
+	defer nc.Unlock()
+	now := nc.clock.Now().UTC()
+
+	// entries are only dropped when they're looked up again, so sweep out expired ones every so often
+	if len(nc.entries) >= nc.sweepAt {
+		for k, e := range nc.entries {
+			if !e.expire.After(now) {
+				delete(nc.entries, k)
+			}
+		}
+		nc.sweepAt = 2*len(nc.entries) + minNegativeSweep
+	}
+
+	nc.entries[key] = negativeEntry{
+		err:    err,
+		expire: now.Add(nc.ttl),
+	}
+}
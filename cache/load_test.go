@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	c := NewCache()
+	var calls int32
+	release := make(chan struct{})
+	loader := func(key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "Loaded " + key, nil
+	}
+
+	results := make(chan Result, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- c.GetOrLoad("Test Key", loader, 5*time.Minute)
+		}()
+	}
+
+	// give the goroutines a chance to pile up behind the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for r := range results {
+		if r.Err != nil || (r.Action != Loaded && r.Action != Retrieved) || r.GetValue() != "Loaded Test Key" {
+			t.Fatalf("Got unexpected result from GetOrLoad: %v", r)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected loader to be called once but it was called %v times", calls)
+	}
+
+	r := c.GetOrLoad("Test Key", loader, 5*time.Minute)
+	if r.Action != Retrieved || r.Err != nil {
+		t.Fatalf("Expected a cache hit after loading key: %v", r)
+	}
+
+	ttl, err := c.GetTTL("Test Key")
+	if err != nil || ttl < 5*time.Minute-marginOfError {
+		t.Fatalf("Got unexpected err or ttl for loaded key: Err: %+v TTL: %s", err, ttl)
+	}
+}
+
+func TestGetOrLoadNegativeCaching(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock), WithNegativeTTL(50*time.Millisecond))
+	loadErr := errors.New("backend unavailable")
+	var calls int32
+	loader := func(key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", loadErr
+	}
+
+	for i := 0; i < 3; i++ {
+		r := c.GetOrLoad("Test Key", loader, 0)
+		if e, ok := r.Err.(ErrLoadFailed); r.Action != Failed || !ok || e.Err != loadErr {
+			t.Fatalf("Expected ErrLoadFailed wrapping the loader's error: %v", r)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected loader failure to be cached but loader was called %v times", calls)
+	}
+
+	clock.Advance(75 * time.Millisecond)
+	c.GetOrLoad("Test Key", loader, 0)
+	if calls != 2 {
+		t.Fatalf("Expected loader to be called again once the failure expired but it was called %v times", calls)
+	}
+
+	if r := c.Get("Test Key"); r.Err == nil {
+		t.Fatalf("Failed loads shouldn't set the key: %v", r)
+	}
+}
@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//latencyBuckets are the upper bounds, in seconds, of the buckets operation latencies are counted in
+var latencyBuckets = []float64{0.000001, 0.000005, 0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+//Metrics counts what the caches it's installed in are doing and serves the counts over HTTP in the Prometheus text
+//exposition format.  One Metrics can be shared by several caches, such as every cache in a Namespaces, in which case it
+//reports their totals.
+type Metrics struct {
+	hits        uint64
+	misses      uint64
+	created     uint64
+	updated     uint64
+	unsets      uint64
+	expirations uint64
+	evictions   [Evicted + 1]uint64
+
+	latencies map[string]*histogram
+	caches    []*memCache
+	sync.Mutex
+}
+
+//NewMetrics returns a newly instantiated Metrics that's ready to be installed with WithMetrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latencies: make(map[string]*histogram),
+	}
+}
+
+//WithMetrics counts what the cache does in m, see Metrics
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// every method is safe to call on a nil Metrics so caches without metrics don't need to check for them
+
+func (m *Metrics) register(c *memCache) {
+	if m == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.caches = append(m.caches, c)
+}
+
+func (m *Metrics) unregister(c *memCache) {
+	if m == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	for i, registered := range m.caches {
+		if registered == c {
+			m.caches = append(m.caches[:i], m.caches[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Metrics) get(hit bool) {
+	if m == nil {
+		return
+	}
+
+	if hit {
+		atomic.AddUint64(&m.hits, 1)
+	} else {
+		atomic.AddUint64(&m.misses, 1)
+	}
+}
+
+func (m *Metrics) set(a action) {
+	if m == nil {
+		return
+	}
+
+	switch a {
+	case Created:
+		atomic.AddUint64(&m.created, 1)
+	case Updated:
+		atomic.AddUint64(&m.updated, 1)
+	}
+}
+
+func (m *Metrics) unset() {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.unsets, 1)
+}
+
+func (m *Metrics) evicted(reason EvictReason) {
+	if m == nil || reason < 0 || int(reason) >= len(m.evictions) {
+		return
+	}
+
+	if reason == Expired {
+		atomic.AddUint64(&m.expirations, 1)
+	}
+	atomic.AddUint64(&m.evictions[reason], 1)
+}
+
+//observe records how long op took
+func (m *Metrics) observe(op string, took time.Duration) {
+	if m == nil {
+		return
+	}
+
+	seconds := took.Seconds()
+	m.Lock()
+	defer m.Unlock()
+	h, ok := m.latencies[op]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.latencies[op] = h
+	}
+
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+//ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//WriteTo writes the metrics to w in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.Lock()
+	caches := append([]*memCache(nil), m.caches...)
+	ops := make([]string, 0, len(m.latencies))
+	latencies := make(map[string]histogram, len(m.latencies))
+	for op, h := range m.latencies {
+		ops = append(ops, op)
+		latencies[op] = histogram{
+			buckets: append([]uint64(nil), h.buckets...),
+			count:   h.count,
+			sum:     h.sum,
+		}
+	}
+	m.Unlock()
+	sort.Strings(ops)
+
+	// the gauges are read from the caches without holding our lock since the caches take it while holding theirs
+	keys, ttls := 0, 0
+	for _, c := range caches {
+		ks := c.keyspace()
+		keys += ks.table.Len()
+		ttls += ks.ttlRegistry.Len()
+	}
+
+	mw := &metricWriter{w: bufio.NewWriter(w)}
+	mw.header("yadc_gets_total", "counter", "Gets by whether the key was found.")
+	mw.sample("yadc_gets_total", `result="hit"`, atomic.LoadUint64(&m.hits))
+	mw.sample("yadc_gets_total", `result="miss"`, atomic.LoadUint64(&m.misses))
+	mw.header("yadc_sets_total", "counter", "Sets by whether the key was created or updated.")
+	mw.sample("yadc_sets_total", `result="created"`, atomic.LoadUint64(&m.created))
+	mw.sample("yadc_sets_total", `result="updated"`, atomic.LoadUint64(&m.updated))
+	mw.header("yadc_unsets_total", "counter", "Keys unset.")
+	mw.sample("yadc_unsets_total", "", atomic.LoadUint64(&m.unsets))
+	mw.header("yadc_expirations_total", "counter", "Keys expired because their TTL elapsed.")
+	mw.sample("yadc_expirations_total", "", atomic.LoadUint64(&m.expirations))
+	mw.header("yadc_evictions_total", "counter", "Values removed from the cache by reason.")
+	for reason := range m.evictions {
+		labels := fmt.Sprintf("reason=%q", EvictReason(reason).String())
+		mw.sample("yadc_evictions_total", labels, atomic.LoadUint64(&m.evictions[reason]))
+	}
+	mw.header("yadc_keys", "gauge", "Keys in the cache.")
+	mw.sample("yadc_keys", "", uint64(keys))
+	mw.header("yadc_ttl_queue_length", "gauge", "Keys waiting to expire.")
+	mw.sample("yadc_ttl_queue_length", "", uint64(ttls))
+
+	mw.header("yadc_operation_duration_seconds", "histogram", "How long operations took.")
+	for _, op := range ops {
+		h := latencies[op]
+		for i, le := range latencyBuckets {
+			labels := fmt.Sprintf("op=%q,le=%q", op, strconv.FormatFloat(le, 'g', -1, 64))
+			mw.sample("yadc_operation_duration_seconds_bucket", labels, h.buckets[i])
+		}
+		mw.sample("yadc_operation_duration_seconds_bucket", fmt.Sprintf(`op=%q,le="+Inf"`, op), h.count)
+		mw.line("yadc_operation_duration_seconds_sum", fmt.Sprintf("op=%q", op), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		mw.sample("yadc_operation_duration_seconds_count", fmt.Sprintf("op=%q", op), h.count)
+	}
+
+	return mw.finish()
+}
+
+//metricWriter writes lines of the exposition format, remembering the first error so callers only have to check it once
+type metricWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (mw *metricWriter) header(name, kind, help string) {
+	mw.printf("# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+}
+
+func (mw *metricWriter) sample(name, labels string, value uint64) {
+	mw.line(name, labels, strconv.FormatUint(value, 10))
+}
+
+func (mw *metricWriter) line(name, labels, value string) {
+	if labels != "" {
+		mw.printf("%v{%v} %v\n", name, labels, value)
+	} else {
+		mw.printf("%v %v\n", name, value)
+	}
+}
+
+func (mw *metricWriter) printf(format string, args ...interface{}) {
+	if mw.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(mw.w, format, args...)
+	mw.n += int64(n)
+	mw.err = err
+}
+
+func (mw *metricWriter) finish() (int64, error) {
+	if mw.err != nil {
+		return mw.n, mw.err
+	}
+
+	return mw.n, mw.w.Flush()
+}
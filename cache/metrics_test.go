@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithMetrics(m), WithClock(clock))
+	c.Set("Test Key 1", "Test Value", 0)
+	c.Set("Test Key 1", "Test Value", 0)
+	c.Set("Test Key 2", "Test Value", 50*time.Millisecond)
+	c.Set("Test Key 3", "Test Value", 5*time.Minute)
+	c.Get("Test Key 1")
+	c.Get("Garbage Key")
+	c.Unset("Test Key 1")
+	clock.Advance(100 * time.Millisecond)
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Couldn't scrape metrics: %+v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Got unexpected content type: %v", ct)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Couldn't read metrics: %+v", err)
+	}
+
+	expected := []string{
+		`# TYPE yadc_gets_total counter`,
+		`yadc_gets_total{result="hit"} 1`,
+		`yadc_gets_total{result="miss"} 1`,
+		`yadc_sets_total{result="created"} 3`,
+		`yadc_sets_total{result="updated"} 1`,
+		`yadc_unsets_total 1`,
+		`yadc_expirations_total 1`,
+		`yadc_evictions_total{reason="replaced"} 1`,
+		`yadc_evictions_total{reason="removed"} 1`,
+		`yadc_keys 1`,
+		`yadc_ttl_queue_length 1`,
+		`# TYPE yadc_operation_duration_seconds histogram`,
+		`yadc_operation_duration_seconds_bucket{op="set",le="+Inf"} 4`,
+		`yadc_operation_duration_seconds_count{op="get"} 2`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Fatalf("Expected metrics to contain %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsAreShared(t *testing.T) {
+	m := NewMetrics()
+	ns := NewNamespaces(WithMetrics(m))
+	ns.Select("a").Set("Test Key", "Test Value", 0)
+	ns.Select("b").Set("Test Key", "Test Value", 0)
+
+	var sb bytes.Buffer
+	if _, err := m.WriteTo(&sb); err != nil {
+		t.Fatalf("Couldn't write metrics: %+v", err)
+	}
+
+	if !strings.Contains(sb.String(), "yadc_keys 2\n") {
+		t.Fatalf("Expected the key count to cover every namespace:\n%s", sb.String())
+	}
+
+	// closed caches stop being reported
+	c := NewCache(WithMetrics(m))
+	c.Set("Other Key", "Test Value", 0)
+	c.(io.Closer).Close()
+	if len(m.caches) != 2 {
+		t.Fatalf("Expected the closed cache to be unregistered but %v caches are registered", len(m.caches))
+	}
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+//ErrSameNamespace is returned when a key is moved to the namespace it's already in
+type ErrSameNamespace string
+
+func (e ErrSameNamespace) Error() string {
+	return fmt.Sprintf("Source and destination namespaces are the same: %v", string(e))
+}
+
+//Namespaces holds several independent caches, each with its own keys and TTLs, so different users of one deployment
+//can use the same key names without colliding.  Namespaces are created the first time they're selected and all share
+//the options Namespaces was created with.
+type Namespaces struct {
+	opts   options
+	caches map[string]*memCache
+	closed bool
+	sync.RWMutex
+}
+
+//NewNamespaces returns a newly instantiated set of Namespaces that's ready to use
+func NewNamespaces(opts ...Option) *Namespaces {
+	return &Namespaces{
+		opts:   newOptions(opts),
+		caches: make(map[string]*memCache),
+	}
+}
+
+//Select will return the cache for the namespace name, creating it if it doesn't exist yet
+func (ns *Namespaces) Select(name string) Cacher {
+	return ns.get(name).cacher()
+}
+
+func (ns *Namespaces) get(name string) *memCache {
+	ns.RLock()
+	c, ok := ns.caches[name]
+	ns.RUnlock()
+	if ok {
+		return c
+	}
+
+	ns.Lock()
+	defer ns.Unlock()
+	if c, ok = ns.caches[name]; !ok {
+		c = newMemCache(ns.opts)
+		if ns.closed {
+			c.Close()
+		}
+		ns.caches[name] = c
+	}
+
+	return c
+}
+
+//Names will return the names of every namespace that has been selected, sorted
+func (ns *Namespaces) Names() []string {
+	ns.RLock()
+	defer ns.RUnlock()
+	names := make([]string, 0, len(ns.caches))
+	for name := range ns.caches {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+//FlushDB will unset every key in the namespace name and return how many keys were removed
+func (ns *Namespaces) FlushDB(name string) int {
+	return ns.get(name).Flush()
+}
+
+//FlushAll will unset every key in every namespace and return how many keys were removed
+func (ns *Namespaces) FlushAll() int {
+	flushed := 0
+	for _, name := range ns.Names() {
+		flushed += ns.FlushDB(name)
+	}
+
+	return flushed
+}
+
+//SwapDB will atomically swap the contents of namespaces a and b, like Redis' SWAPDB.  Anyone using either namespace will
+//see the other namespace's keys straight away.  Closed namespaces can't be swapped.
+func (ns *Namespaces) SwapDB(a, b string) error {
+	if a == b {
+		return nil
+	}
+
+	// lock the namespaces in name order so two swaps can never deadlock each other
+	first, second := ns.get(a), ns.get(b)
+	if b < a {
+		first, second = second, first
+	}
+
+	first.ksLock.Lock()
+	defer first.ksLock.Unlock()
+	second.ksLock.Lock()
+	defer second.ksLock.Unlock()
+	if first.ks.closed || second.ks.closed {
+		return ErrClosed("swap")
+	}
+
+	first.ks, second.ks = second.ks, first.ks
+	return nil
+}
+
+//Move will atomically move key along with its TTL from the namespace from to the namespace to, like Redis' MOVE.  The
+//Result's Action will be Skipped if key already exists in to.
+func (ns *Namespaces) Move(key, from, to string) Result {
+	if from == to {
+		return Result{
+			Action: Failed,
+			Err:    ErrSameNamespace(from),
+		}
+	}
+
+	src, dst := ns.get(from).keyspace(), ns.get(to).keyspace()
+	if src.closed || dst.closed {
+		return Result{
+			Action: Failed,
+			Err:    ErrClosed("move " + key),
+		}
+	}
+
+	var r Result
+	atomicPair(src, dst, func(st, dt HashTable) {
+		r = src.transfer(st, dst, dt, key, key, false, true)
+	})
+
+	return r
+}
+
+//Copy will atomically copy key along with its TTL from the namespace from to newKey in the namespace to, like Redis'
+//COPY with DB.  If newKey already exists the Result's Action will be Skipped unless replace is true.
+func (ns *Namespaces) Copy(from, key, to, newKey string, replace bool) Result {
+	src, dst := ns.get(from).keyspace(), ns.get(to).keyspace()
+	if src.closed || dst.closed {
+		return Result{
+			Action: Failed,
+			Err:    ErrClosed("copy " + key),
+		}
+	}
+
+	if src == dst && key == newKey {
+		return Result{
+			Action: Failed,
+			Err:    ErrSameKey(key),
+		}
+	}
+
+	var r Result
+	atomicPair(src, dst, func(st, dt HashTable) {
+		r = src.transfer(st, dst, dt, key, newKey, replace, false)
+	})
+
+	if r.prev != nil {
+		ns.get(to).evicted(*r.prev, Replaced)
+	}
+
+	return r
+}
+
+//KeyCounts will return the number of keys in every namespace that has been selected
+func (ns *Namespaces) KeyCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, name := range ns.Names() {
+		counts[name] = ns.get(name).Len()
+	}
+
+	return counts
+}
+
+//Close will close every namespace, see a Cache's Close.  Namespaces selected afterwards are already closed.  Only the first
+//call closes the namespaces, later calls return ErrClosed.
+func (ns *Namespaces) Close() error {
+	ns.Lock()
+	if ns.closed {
+		ns.Unlock()
+		return ErrClosed("close")
+	}
+
+	ns.closed = true
+	caches := make([]*memCache, 0, len(ns.caches))
+	for _, c := range ns.caches {
+		caches = append(caches, c)
+	}
+	ns.Unlock()
+
+	// closing calls the evict hooks, which are free to call back into the namespaces
+	for _, c := range caches {
+		c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespacesAreIndependent(t *testing.T) {
+	ns := NewNamespaces()
+	ns.Select("team-a").Set("Test Key", "A", 0)
+	ns.Select("team-b").Set("Test Key", "B", 5*time.Minute)
+
+	if r := ns.Select("team-a").Get("Test Key"); r.GetValue() != "A" {
+		t.Fatalf("Expected team-a's value but got %v", r)
+	}
+
+	if r := ns.Select("team-b").Get("Test Key"); r.GetValue() != "B" {
+		t.Fatalf("Expected team-b's value but got %v", r)
+	}
+
+	if ttl, _ := ns.Select("team-a").GetTTL("Test Key"); ttl != NoTTL {
+		t.Fatalf("team-b's TTL leaked into team-a: %s", ttl)
+	}
+
+	if r := ns.Select("team-c").Get("Test Key"); r.Err == nil {
+		t.Fatalf("Found a key in a new namespace: %v", r)
+	}
+
+	counts := ns.KeyCounts()
+	if counts["team-a"] != 1 || counts["team-b"] != 1 || counts["team-c"] != 0 || len(counts) != 3 {
+		t.Fatalf("Got unexpected key counts: %v", counts)
+	}
+}
+
+func TestFlushDB(t *testing.T) {
+	evicted := make(map[string]EvictReason)
+	clock := NewFakeClock(time.Now())
+	ns := NewNamespaces(WithClock(clock), OnEvict(func(key, value string, reason EvictReason) {
+		evicted[key] = reason
+	}))
+
+	a, b := ns.Select("a"), ns.Select("b")
+	a.Set("Test Key 1", "Test Value", 50*time.Millisecond)
+	a.Set("Test Key 2", "Test Value", 0)
+	b.Set("Test Key 3", "Test Value", 0)
+
+	if flushed := ns.FlushDB("a"); flushed != 2 {
+		t.Fatalf("Expected 2 keys to be flushed but got %v", flushed)
+	}
+
+	if a.Len() != 0 || b.Len() != 1 {
+		t.Fatalf("Expected only a to be flushed, got a: %v b: %v", a.Len(), b.Len())
+	}
+
+	if evicted["Test Key 1"] != Removed || evicted["Test Key 2"] != Removed {
+		t.Fatalf("Expected flushed keys to be reported as removed: %v", evicted)
+	}
+
+	// the flushed TTL mustn't expire a key set again afterwards
+	a.Set("Test Key 1", "New Value", 0)
+	clock.Advance(100 * time.Millisecond)
+	if r := a.Get("Test Key 1"); r.GetValue() != "New Value" {
+		t.Fatalf("A flushed TTL expired a new key: %v", r)
+	}
+
+	if flushed := ns.FlushAll(); flushed != 2 {
+		t.Fatalf("Expected 2 keys to be flushed from every namespace but got %v", flushed)
+	}
+}
+
+func TestSwapDB(t *testing.T) {
+	ns := NewNamespaces()
+	a, b := ns.Select("a"), ns.Select("b")
+	a.Set("Test Key", "A", 5*time.Minute)
+	b.Set("Other Key", "B", 0)
+
+	if err := ns.SwapDB("a", "b"); err != nil {
+		t.Fatalf("Couldn't swap namespaces: %+v", err)
+	}
+
+	if r := a.Get("Other Key"); r.GetValue() != "B" {
+		t.Fatalf("Expected a to have b's keys after the swap: %v", r)
+	}
+
+	if r := b.Get("Test Key"); r.GetValue() != "A" {
+		t.Fatalf("Expected b to have a's keys after the swap: %v", r)
+	}
+
+	if ttl, err := b.GetTTL("Test Key"); err != nil || ttl <= 0 {
+		t.Fatalf("Expected the TTL to be swapped with its key: %s %v", ttl, err)
+	}
+
+	if a.Len() != 1 || b.Len() != 1 {
+		t.Fatalf("Got unexpected lengths after the swap, a: %v b: %v", a.Len(), b.Len())
+	}
+}
+
+func TestMove(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	ns := NewNamespaces(WithClock(clock))
+	a, b := ns.Select("a"), ns.Select("b")
+	a.Set("Test Key", "Test Value", 50*time.Millisecond, SoftTTL(25*time.Millisecond))
+	a.Set("Existing Key", "A", 0)
+	b.Set("Existing Key", "B", 0)
+
+	tests := []struct {
+		key, from, to string
+		expected      action
+	}{
+		{"Test Key", "a", "b", Created},
+		{"Existing Key", "a", "b", Skipped},
+		{"Garbage Key", "a", "b", Failed},
+		{"Existing Key", "a", "a", Failed},
+	}
+
+	for _, test := range tests {
+		if r := ns.Move(test.key, test.from, test.to); r.Action != test.expected {
+			t.Fatalf("Expected moving %v from %v to %v to be %v but got %v", test.key, test.from, test.to, test.expected, r)
+		}
+	}
+
+	if r := a.Get("Test Key"); r.Err == nil {
+		t.Fatalf("Moved key is still in the source namespace: %v", r)
+	}
+
+	info, err := b.GetTTLInfo("Test Key")
+	if err != nil || info.SoftTTL != 25*time.Millisecond {
+		t.Fatalf("Expected the soft TTL to move with the key: %v %v", info, err)
+	}
+
+	if r := b.Get("Existing Key"); r.GetValue() != "B" {
+		t.Fatalf("Move overwrote an existing key: %v", r)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if r := b.Get("Test Key"); r.Err == nil {
+		t.Fatalf("Expected the moved key to expire in its new namespace: %v", r)
+	}
+}
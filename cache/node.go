@@ -1,19 +1,40 @@
 package cache
 
 import (
+	"sync/atomic"
 	"time"
 )
 
+//StringType is the type of a key holding a string value.  It's currently the only type of value the cache holds.
+const StringType = "string"
+
+//NoneType is returned by Type for a key that doesn't exist, like Redis' none
+const NoneType = "none"
+
 type node struct {
 	key     string
 	value   string
 	created time.Time
+	version uint64
 }
 
-func newRecord(k, v string) *node {
+// lastVersion is bumped every time a key is set so every write gets a unique version, even across deletes and tables
+var lastVersion uint64
+
+func nextVersion() uint64 {
+	return atomic.AddUint64(&lastVersion, 1)
+}
+
+func newRecord(k, v string, created time.Time) *node {
 	return &node{
 		key:     k,
 		value:   v,
-		created: time.Now().UTC(),
+		created: created,
+		version: nextVersion(),
 	}
 }
+
+//valueType returns the name of the type of value held by the node
+func (n node) valueType() string {
+	return StringType
+}
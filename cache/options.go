@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+//ErrInvalidOptions indicates options were provided that can't be used together
+type ErrInvalidOptions string
+
+func (e ErrInvalidOptions) Error() string {
+	return fmt.Sprintf("Invalid options: %v", string(e))
+}
+
+//Option configures a Cacher when it is created with NewCache
+type Option func(*options)
+
+type options struct {
+	evictHooks  []EvictFunc
+	negativeTTL time.Duration
+	loader      Loader
+	newTable    func(clock Clock) HashTable
+	ordered     bool
+	metrics     *Metrics
+
+	slowlogThreshold time.Duration
+	slowlogLen       int
+	clientName       string
+
+	tracer Tracer
+	clock  Clock
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		negativeTTL: defaultNegativeTTL,
+		newTable:    newTable,
+
+		slowlogThreshold: DefaultSlowlogThreshold,
+		slowlogLen:       DefaultSlowlogLen,
+
+		tracer: NoopTracer{},
+		clock:  systemClock{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+//OnEvict registers a hook that is called whenever a key is removed from the cache.  Hooks are always called outside of
+//the cache's locks so they are free to call back into the cache.
+func OnEvict(fn EvictFunc) Option {
+	return func(o *options) {
+		o.evictHooks = append(o.evictHooks, fn)
+	}
+}
+
+//WithNegativeTTL sets how long GetOrLoad remembers that a Loader failed for a key.  A TTL less than or equal to zero
+//disables caching of loader failures.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+//WithLoader registers the Loader used to refresh keys in the background once they've outlived their soft TTL
+func WithLoader(loader Loader) Option {
+	return func(o *options) {
+		o.loader = loader
+	}
+}
+
+//WithOrderedKeys stores keys in order, which is needed for Range, Prefix, UnsetRange and UnsetPrefix
+func WithOrderedKeys() Option {
+	return func(o *options) {
+		o.newTable = newOrderedTable
+		o.ordered = true
+	}
+}
+
+//SetOption configures a single call to Set
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	softTTL   time.Duration
+	sliding   bool
+	keepTTL   bool
+	ifAbsent  bool
+	ifPresent bool
+	tags      []string
+}
+
+func newSetOptions(opts []SetOption) setOptions {
+	so := setOptions{}
+	for _, opt := range opts {
+		opt(&so)
+	}
+
+	return so
+}
+
+//validate makes sure the options make sense together with the ttl passed to Set
+func (so setOptions) validate(ttl time.Duration) error {
+	if so.softTTL != 0 && (so.softTTL < 0 || so.softTTL >= ttl || so.sliding) {
+		return ErrInvalidTTL(so.softTTL)
+	}
+
+	if (so.sliding && ttl <= 0) || (so.keepTTL && (ttl != 0 || so.softTTL != 0 || so.sliding)) {
+		return ErrInvalidTTL(ttl)
+	}
+
+	if so.ifAbsent && so.ifPresent {
+		return ErrInvalidOptions("IfAbsent and IfPresent can't be combined")
+	}
+
+	return nil
+}
+
+//SoftTTL marks the key as stale once ttl has elapsed.  Stale keys are still returned by Get, flagged as stale, until the
+//key's TTL passed to Set elapses, and are refreshed in the background by the cache's Loader if one was registered.
+func SoftTTL(ttl time.Duration) SetOption {
+	return func(so *setOptions) {
+		so.softTTL = ttl
+	}
+}
+
+//Sliding makes the TTL passed to Set an idle timeout.  The key's TTL is reset every time the key is retrieved, so the
+//key only expires once it hasn't been retrieved for the full TTL.  Sliding can't be combined with SoftTTL.
+func Sliding() SetOption {
+	return func(so *setOptions) {
+		so.sliding = true
+	}
+}
+
+//KeepTTL keeps the TTL already registered for the key when it's overwritten instead of clearing it.  The TTL passed to Set
+//must be zero when keeping the existing TTL.
+func KeepTTL() SetOption {
+	return func(so *setOptions) {
+		so.keepTTL = true
+	}
+}
+
+//IfAbsent only sets the key if it doesn't already exist, like Redis' NX.  If the key exists the Result's Action will be
+//Skipped.
+func IfAbsent() SetOption {
+	return func(so *setOptions) {
+		so.ifAbsent = true
+	}
+}
+
+//IfPresent only sets the key if it already exists, like Redis' XX.  If the key doesn't exist the Result's Action will be
+//Skipped.
+func IfPresent() SetOption {
+	return func(so *setOptions) {
+		so.ifPresent = true
+	}
+}
+
+//Tags tags the key so it can be unset along with every other key sharing one of the tags by InvalidateTag.  Setting a key
+//replaces any tags it already had.
+func Tags(tags ...string) SetOption {
+	return func(so *setOptions) {
+		so.tags = append(so.tags, tags...)
+	}
+}
@@ -0,0 +1,355 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//OrderedHashTable is a HashTable that keeps its keys in order, so it can cheaply find every key in a range or under a
+//prefix.  The callbacks passed to Range and Prefix are called while holding the table's lock so they must not call back
+//into the table.
+type OrderedHashTable interface {
+	HashTable
+	//Range calls fn with every key from start up to, but not including, end in order until fn returns false.  An empty
+	//end means there's no upper bound.
+	Range(start, end string, fn func(r Result) bool)
+	//Prefix calls fn with every key starting with prefix in order until fn returns false.
+	Prefix(prefix string, fn func(r Result) bool)
+}
+
+//ErrNotOrdered is returned when an operation that needs an OrderedHashTable is used on a cache that isn't ordered
+type ErrNotOrdered string
+
+func (e ErrNotOrdered) Error() string {
+	return fmt.Sprintf("%v requires an ordered table, see WithOrderedKeys", string(e))
+}
+
+//OrderedCacher is a Cacher that keeps its keys in order, so it can walk and unset every key in a range or under a
+//prefix.  Only caches created WithOrderedKeys are OrderedCachers, so callers should type assert for it:
+//
+//	if oc, ok := c.(OrderedCacher); ok {
+//		oc.Prefix("tenant:1:", fn)
+//	}
+type OrderedCacher interface {
+	Cacher
+	Range(start, end string, fn func(r Result) bool) error
+	Prefix(prefix string, fn func(r Result) bool) error
+	UnsetRange(start, end string) ([]Result, error)
+	UnsetPrefix(prefix string) ([]Result, error)
+}
+
+//orderedCache is a memCache with an ordered table, it adds the OrderedCacher operations
+type orderedCache struct {
+	*memCache
+}
+
+//cacher returns c as the interfaces it implements, an OrderedCacher if its table is ordered
+func (c *memCache) cacher() Cacher {
+	if c.opts.ordered {
+		return &orderedCache{c}
+	}
+
+	return c
+}
+
+type orderedHashTable struct {
+	tree  btree
+	clock Clock
+	sync.RWMutex
+}
+
+func newOrderedTable(clock Clock) HashTable {
+	return &orderedHashTable{clock: clock}
+}
+
+func (t *orderedHashTable) Set(key, value string) Result {
+	t.Lock()
+	defer t.Unlock()
+	return t.set(key, value)
+}
+
+func (t *orderedHashTable) Unset(key string) Result {
+	t.Lock()
+	defer t.Unlock()
+	return t.unset(key)
+}
+
+func (t *orderedHashTable) Get(key string) Result {
+	t.RLock()
+	defer t.RUnlock()
+	return t.get(key)
+}
+
+func (t *orderedHashTable) Atomic(fn func(t HashTable)) {
+	t.Lock()
+	defer t.Unlock()
+	fn(lockedOrderedHashTable{t})
+}
+
+func (t *orderedHashTable) Scan(cursor string, count int) (string, []Result, error) {
+	t.RLock()
+	defer t.RUnlock()
+	return t.scan(cursor, count)
+}
+
+func (t *orderedHashTable) Len() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.tree.count
+}
+
+func (t *orderedHashTable) Range(start, end string, fn func(r Result) bool) {
+	t.RLock()
+	defer t.RUnlock()
+	t.ascendRange(start, end, fn)
+}
+
+func (t *orderedHashTable) Prefix(prefix string, fn func(r Result) bool) {
+	t.RLock()
+	defer t.RUnlock()
+	t.ascendPrefix(prefix, fn)
+}
+
+func (t *orderedHashTable) set(key, value string) Result {
+	if n := t.tree.get(key); n != nil {
+		prev := *n
+		n.value = value
+		n.created = t.clock.Now().UTC()
+		n.version = nextVersion()
+		return Result{
+			n:      *n,
+			prev:   &prev,
+			Action: Updated,
+		}
+	}
+
+	n := newRecord(key, value, t.clock.Now().UTC())
+	t.tree.insert(n)
+	return Result{
+		n:      *n,
+		Action: Created,
+	}
+}
+
+func (t *orderedHashTable) unset(key string) Result {
+	n := t.tree.remove(key)
+	if n == nil {
+		return Result{
+			Action: Failed,
+			Err:    ErrKeyNotFound(key),
+		}
+	}
+
+	return Result{
+		Action: Deleted,
+		n:      *n,
+	}
+}
+
+func (t *orderedHashTable) get(key string) Result {
+	n := t.tree.get(key)
+	if n == nil {
+		return Result{
+			Action: Failed,
+			Err:    ErrKeyNotFound(key),
+		}
+	}
+
+	return Result{
+		Action: Retrieved,
+		n:      *n,
+	}
+}
+
+//scan uses the next key to return, prefixed with a k so it's never empty, as the cursor.  Keys are returned in order so
+//every key that exists for the whole scan is returned exactly once.
+func (t *orderedHashTable) scan(cursor string, count int) (string, []Result, error) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	start := ""
+	if cursor != "" {
+		if !strings.HasPrefix(cursor, "k") {
+			return "", nil, ErrInvalidCursor(cursor)
+		}
+		start = cursor[1:]
+	}
+
+	results := make([]Result, 0, count)
+	next := ""
+	t.tree.ascend(start, func(n *node) bool {
+		if len(results) == count {
+			next = "k" + n.key
+			return false
+		}
+
+		results = append(results, Result{
+			Action: Retrieved,
+			n:      *n,
+		})
+		return true
+	})
+
+	return next, results, nil
+}
+
+func (t *orderedHashTable) ascendRange(start, end string, fn func(r Result) bool) {
+	t.tree.ascend(start, func(n *node) bool {
+		if end != "" && n.key >= end {
+			return false
+		}
+
+		return fn(Result{
+			Action: Retrieved,
+			n:      *n,
+		})
+	})
+}
+
+func (t *orderedHashTable) ascendPrefix(prefix string, fn func(r Result) bool) {
+	t.tree.ascend(prefix, func(n *node) bool {
+		if !strings.HasPrefix(n.key, prefix) {
+			return false
+		}
+
+		return fn(Result{
+			Action: Retrieved,
+			n:      *n,
+		})
+	})
+}
+
+//lockedOrderedHashTable is handed to Atomic's callback and uses the table without locking since the lock is already held
+type lockedOrderedHashTable struct {
+	t *orderedHashTable
+}
+
+func (l lockedOrderedHashTable) Set(key, value string) Result { return l.t.set(key, value) }
+
+func (l lockedOrderedHashTable) Unset(key string) Result { return l.t.unset(key) }
+
+func (l lockedOrderedHashTable) Get(key string) Result { return l.t.get(key) }
+
+func (l lockedOrderedHashTable) Atomic(fn func(t HashTable)) { fn(l) }
+
+func (l lockedOrderedHashTable) Scan(cursor string, count int) (string, []Result, error) {
+	return l.t.scan(cursor, count)
+}
+
+func (l lockedOrderedHashTable) Len() int { return l.t.tree.count }
+
+func (l lockedOrderedHashTable) Range(start, end string, fn func(r Result) bool) {
+	l.t.ascendRange(start, end, fn)
+}
+
+func (l lockedOrderedHashTable) Prefix(prefix string, fn func(r Result) bool) {
+	l.t.ascendPrefix(prefix, fn)
+}
+
+// rangeBatch is how many keys Range and Prefix read from the table per lock so the table isn't locked while calling back
+const rangeBatch = 100
+
+//Range will call fn with every key from start up to, but not including, end in order until fn returns false.  An empty
+//end means there's no upper bound.  Keys are read from the table in batches and fn is called without holding any locks,
+//so fn is free to call back into the cache.  Range requires a cache created WithOrderedKeys.
+func (c *orderedCache) Range(start, end string, fn func(r Result) bool) error {
+	defer c.observe("range", start, time.Now())
+	return c.ascend("Range", start, func(key string) bool {
+		return end == "" || key < end
+	}, fn)
+}
+
+//Prefix will call fn with every key starting with prefix in order until fn returns false.  Like Range, fn is called
+//without holding any locks.  Prefix requires a cache created WithOrderedKeys.
+func (c *orderedCache) Prefix(prefix string, fn func(r Result) bool) error {
+	defer c.observe("prefix", prefix, time.Now())
+	return c.ascend("Prefix", prefix, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}, fn)
+}
+
+func (c *orderedCache) ascend(op, start string, within func(key string) bool, fn func(r Result) bool) error {
+	ks := c.keyspace()
+	if ks.closed {
+		return ErrClosed(op)
+	}
+
+	ot, ok := ks.table.(OrderedHashTable)
+	if !ok {
+		return ErrNotOrdered(op)
+	}
+
+	for {
+		batch := make([]Result, 0, rangeBatch)
+		ot.Range(start, "", func(r Result) bool {
+			if !within(r.n.key) {
+				return false
+			}
+
+			batch = append(batch, r)
+			return len(batch) < rangeBatch
+		})
+
+		for _, r := range batch {
+			if !fn(r) {
+				return nil
+			}
+		}
+
+		if len(batch) < rangeBatch {
+			return nil
+		}
+
+		// the smallest key after the last one we saw
+		start = batch[len(batch)-1].n.key + "\x00"
+	}
+}
+
+//UnsetRange will unset every key from start up to, but not including, end and return a Result for each key unset.  Their
+//TTLs are unregistered along with them.  UnsetRange requires a cache created WithOrderedKeys.
+func (c *orderedCache) UnsetRange(start, end string) ([]Result, error) {
+	defer c.observe("unsetrange", start, time.Now())
+	return c.unsetOrdered("UnsetRange", func(ot OrderedHashTable, fn func(r Result) bool) {
+		ot.Range(start, end, fn)
+	})
+}
+
+//UnsetPrefix will unset every key starting with prefix and return a Result for each key unset.  Their TTLs are
+//unregistered along with them.  UnsetPrefix requires a cache created WithOrderedKeys.
+func (c *orderedCache) UnsetPrefix(prefix string) ([]Result, error) {
+	defer c.observe("unsetprefix", prefix, time.Now())
+	return c.unsetOrdered("UnsetPrefix", func(ot OrderedHashTable, fn func(r Result) bool) {
+		ot.Prefix(prefix, fn)
+	})
+}
+
+func (c *orderedCache) unsetOrdered(op string, walk func(ot OrderedHashTable, fn func(r Result) bool)) ([]Result, error) {
+	ks := c.keyspace()
+	if ks.closed {
+		return nil, ErrClosed(op)
+	}
+
+	if _, ok := ks.table.(OrderedHashTable); !ok {
+		return nil, ErrNotOrdered(op)
+	}
+
+	var results []Result
+	ks.table.Atomic(func(t HashTable) {
+		keys := make([]string, 0)
+		walk(t.(OrderedHashTable), func(r Result) bool {
+			keys = append(keys, r.n.key)
+			return true
+		})
+
+		results = make([]Result, 0, len(keys))
+		for _, key := range keys {
+			results = append(results, ks.unset(t, key))
+		}
+	})
+
+	c.evictedResults(results)
+	return results, nil
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrderedTable(t *testing.T) {
+	table := newOrderedTable(systemClock{}).(OrderedHashTable)
+	for _, key := range []string{"tenant:2:user:1", "tenant:1:user:2", "tenant:1:user:1", "tenant:10:user:1", "other"} {
+		if r := table.Set(key, "Value "+key); r.Action != Created || r.Err != nil {
+			t.Fatalf("Couldn't set key %v: %v", key, r)
+		}
+	}
+
+	if r := table.Set("other", "New Value"); r.Action != Updated || r.prev == nil || r.prev.value != "Value other" {
+		t.Fatalf("Got unexpected result updating key: %v", r)
+	}
+
+	if r := table.Get("other"); r.Err != nil || r.GetValue() != "New Value" {
+		t.Fatalf("Got unexpected result getting key: %v", r)
+	}
+
+	collect := func(walk func(fn func(r Result) bool)) []string {
+		keys := make([]string, 0)
+		walk(func(r Result) bool {
+			keys = append(keys, r.GetKey())
+			return true
+		})
+		return keys
+	}
+
+	prefixed := collect(func(fn func(r Result) bool) { table.Prefix("tenant:1:", fn) })
+	if fmt.Sprint(prefixed) != "[tenant:1:user:1 tenant:1:user:2]" {
+		t.Fatalf("Got unexpected keys under prefix: %v", prefixed)
+	}
+
+	ranged := collect(func(fn func(r Result) bool) { table.Range("tenant:1", "tenant:2", fn) })
+	if fmt.Sprint(ranged) != "[tenant:10:user:1 tenant:1:user:1 tenant:1:user:2]" {
+		t.Fatalf("Got unexpected keys in range: %v", ranged)
+	}
+
+	if r := table.Unset("tenant:1:user:1"); r.Action != Deleted || r.Err != nil {
+		t.Fatalf("Couldn't unset key: %v", r)
+	}
+
+	if r := table.Unset("tenant:1:user:1"); r.Action != Failed {
+		t.Fatalf("Expected unsetting a missing key to fail: %v", r)
+	}
+}
+
+func TestOrderedCache(t *testing.T) {
+	c, ok := NewCache(WithOrderedKeys()).(OrderedCacher)
+	if !ok {
+		t.Fatalf("Expected a cache with ordered keys to be an OrderedCacher")
+	}
+
+	for i := 0; i < 250; i++ {
+		c.Set(fmt.Sprintf("tenant:1:user:%03d", i), "Value", 5*time.Minute)
+		c.Set(fmt.Sprintf("tenant:2:user:%03d", i), "Value", 0)
+	}
+
+	// walk past a batch boundary and call back into the cache from the callback
+	seen := 0
+	err := c.Prefix("tenant:1:", func(r Result) bool {
+		if c.Get(r.GetKey()).Err != nil {
+			t.Fatalf("Couldn't get key %v from inside of Prefix", r.GetKey())
+		}
+		if expected := fmt.Sprintf("tenant:1:user:%03d", seen); r.GetKey() != expected {
+			t.Fatalf("Got key %v from Prefix, expected %v", r.GetKey(), expected)
+		}
+		seen++
+		return true
+	})
+
+	if err != nil || seen != 250 {
+		t.Fatalf("Expected to see 250 keys under prefix but saw %v: %+v", seen, err)
+	}
+
+	seen = 0
+	c.Range("tenant:1:user:100", "tenant:1:user:110", func(r Result) bool {
+		seen++
+		return true
+	})
+
+	if seen != 10 {
+		t.Fatalf("Expected to see 10 keys in range but saw %v", seen)
+	}
+
+	results, err := c.UnsetPrefix("tenant:1:")
+	if err != nil || len(results) != 250 {
+		t.Fatalf("Expected to unset 250 keys: %v, %+v", len(results), err)
+	}
+
+	if ttl, _ := c.GetTTL("tenant:1:user:000"); ttl != NoKey {
+		t.Fatalf("Expected unset key to have no TTL but got %s", ttl)
+	}
+
+	if n := len(c.(*orderedCache).keyspace().ttlRegistry.ttlByKey); n != 0 {
+		t.Fatalf("Expected every TTL to be unregistered but %v are left", n)
+	}
+
+	results, err = c.UnsetRange("tenant:2:user:000", "tenant:2:user:010")
+	if err != nil || len(results) != 10 {
+		t.Fatalf("Expected to unset 10 keys: %v, %+v", len(results), err)
+	}
+
+	if seen := scanAll(t, c, ""); len(seen) != 240 {
+		t.Fatalf("Expected 240 keys to be left but scanned %v", len(seen))
+	}
+}
+
+func TestUnorderedCache(t *testing.T) {
+	if _, ok := NewCache().(OrderedCacher); ok {
+		t.Fatalf("Expected a cache without ordered keys not to be an OrderedCacher")
+	}
+
+	if _, ok := NewContextCache(WithOrderedKeys()).(OrderedCacher); !ok {
+		t.Fatalf("Expected a context cache with ordered keys to be an OrderedCacher")
+	}
+
+	ns := NewNamespaces(WithOrderedKeys())
+	if _, ok := ns.Select("a").(OrderedCacher); !ok {
+		t.Fatalf("Expected a namespace with ordered keys to be an OrderedCacher")
+	}
+}
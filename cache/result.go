@@ -17,13 +17,41 @@ const (
 	Deleted action = iota
 	//Retrieved indicates the attempted action returning a value
 	Retrieved action = iota
+	//Loaded indicates the value was missing from the cache and was loaded and set by a Loader
+	Loaded action = iota
+	//Skipped indicates the attempted action's condition wasn't met so nothing was changed
+	Skipped action = iota
 )
 
+func (a action) String() string {
+	switch a {
+	case Failed:
+		return "failed"
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Deleted:
+		return "deleted"
+	case Retrieved:
+		return "retrieved"
+	case Loaded:
+		return "loaded"
+	case Skipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
 //Result represents a result from the cache table.  Err will be nil when the action was successful and an action of Failed will always have a non-nill Err
+//Stale will be true when the value was retrieved after the key's soft TTL had elapsed
 type Result struct {
 	Action action
 	n      node
+	prev   *node
 	Err    error
+	Stale  bool
 }
 
 //GetValue gets the value of the Node from the cache
@@ -40,3 +68,8 @@ func (r Result) GetKey() string {
 func (r Result) GetCreatedTime() time.Time {
 	return r.n.created
 }
+
+//GetVersion gets the version of the Node from the cache.  Versions increase every time a key is set and are never reused.
+func (r Result) GetVersion() uint64 {
+	return r.n.version
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"time"
+)
+
+//ScanOption configures a single call to Scan
+type ScanOption func(*scanOptions)
+
+type scanOptions struct {
+	valueType string
+}
+
+//OfType only returns keys holding values of the provided type, such as StringType
+func OfType(valueType string) ScanOption {
+	return func(so *scanOptions) {
+		so.valueType = valueType
+	}
+}
+
+//Scan will return a batch of keys matching the glob pattern match, starting from cursor, along with the cursor to pass to
+//the next call.  Start a scan with an empty cursor and keep calling Scan until it returns an empty cursor.  count is a
+//hint of how many keys to look at per call, so a call may return more keys or, after filtering, fewer or none at all.
+//Every key that exists for the whole scan is returned exactly once.
+func (c *memCache) Scan(cursor, match string, count int, opts ...ScanOption) (string, []string, error) {
+	defer c.observe("scan", match, time.Now())
+	ks := c.keyspace()
+	so := scanOptions{}
+	for _, opt := range opts {
+		opt(&so)
+	}
+
+	next, results, err := ks.table.Scan(cursor, count)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keys := make([]string, 0, len(results))
+	for _, r := range results {
+		if so.valueType != "" && r.n.valueType() != so.valueType {
+			continue
+		}
+
+		if matchGlob(match, r.n.key) {
+			keys = append(keys, r.n.key)
+		}
+	}
+
+	return next, keys, nil
+}
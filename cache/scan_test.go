@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func scanAll(t *testing.T, c Cacher, match string, opts ...ScanOption) map[string]int {
+	seen := make(map[string]int)
+	cursor := ""
+	for {
+		next, keys, err := c.Scan(cursor, match, 10, opts...)
+		if err != nil {
+			t.Fatalf("Couldn't scan from cursor %v: %+v", cursor, err)
+		}
+
+		for _, key := range keys {
+			seen[key]++
+		}
+
+		if next == "" {
+			return seen
+		}
+		cursor = next
+	}
+}
+
+func TestScan(t *testing.T) {
+	c := NewCache()
+	for i := 0; i < 500; i++ {
+		c.Set(fmt.Sprintf("stable:%v", i), "Value", 0)
+	}
+
+	// churn other keys while we scan, the stable keys should still all be returned exactly once
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			key := fmt.Sprintf("churn:%v", i%200)
+			if i%2 == 0 {
+				c.Set(key, "Value", 0)
+			} else {
+				c.Unset(key)
+			}
+		}
+	}()
+
+	seen := scanAll(t, c, "stable:*")
+	close(stop)
+	wg.Wait()
+
+	if len(seen) != 500 {
+		t.Fatalf("Expected to see 500 stable keys but saw %v", len(seen))
+	}
+
+	for key, count := range seen {
+		if count != 1 {
+			t.Fatalf("Saw key %v %v times", key, count)
+		}
+	}
+}
+
+func TestScanFilters(t *testing.T) {
+	c := NewCache()
+	c.Set("tenant:1:user:1", "Value", 0)
+	c.Set("tenant:1:user:2", "Value", 0)
+	c.Set("tenant:2:user:1", "Value", 0)
+
+	if seen := scanAll(t, c, "tenant:1:*"); len(seen) != 2 || seen["tenant:1:user:1"] != 1 || seen["tenant:1:user:2"] != 1 {
+		t.Fatalf("Got unexpected keys matching tenant:1:*: %v", seen)
+	}
+
+	if seen := scanAll(t, c, "", OfType(StringType)); len(seen) != 3 {
+		t.Fatalf("Expected every key to be a string but got: %v", seen)
+	}
+
+	if seen := scanAll(t, c, "", OfType("hash")); len(seen) != 0 {
+		t.Fatalf("Expected no keys to be hashes but got: %v", seen)
+	}
+
+	if _, _, err := c.Scan("garbage", "", 10); err == nil {
+		t.Fatalf("Expected an error scanning from an invalid cursor")
+	}
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultSlowlogThreshold is how long an operation must take to be logged unless WithSlowlog says otherwise, like
+	//Redis' slowlog-log-slower-than
+	DefaultSlowlogThreshold = 10 * time.Millisecond
+	//DefaultSlowlogLen is how many slow operations are kept unless WithSlowlog says otherwise, like Redis'
+	//slowlog-max-len
+	DefaultSlowlogLen = 128
+	//maxSlowlogKeyLen is how much of a key is kept in the slowlog, longer keys are truncated
+	maxSlowlogKeyLen = 128
+)
+
+//SlowlogEntry describes an operation that took longer than the slowlog's threshold
+type SlowlogEntry struct {
+	//ID is unique for each entry and increases with every slow operation logged
+	ID uint64
+	//Time is when the operation started
+	Time time.Time
+	//Duration is how long the operation took
+	Duration time.Duration
+	//Op is the name of the operation, such as set
+	Op string
+	//Key is the key the operation was called with, or the first key for operations on several keys, truncated if it's
+	//too long
+	Key string
+	//Client identifies who called the operation, see ContextWithClient and WithClientName
+	Client string
+}
+
+//WithSlowlog logs operations that take longer than threshold, keeping the most recent maxLen of them.  A threshold of
+//zero logs every operation and a negative threshold disables the slowlog.
+func WithSlowlog(threshold time.Duration, maxLen int) Option {
+	return func(o *options) {
+		o.slowlogThreshold = threshold
+		o.slowlogLen = maxLen
+	}
+}
+
+//WithClientName sets the client identity recorded with the cache's slow operations when the call doesn't say who made
+//it, see ContextWithClient
+func WithClientName(name string) Option {
+	return func(o *options) {
+		o.clientName = name
+	}
+}
+
+type clientKey struct{}
+
+//ContextWithClient returns a copy of ctx identifying the client that makes calls with it, which the slowlog records
+//with operations called through ContextCacher.  A server would set it once for each connection.
+func ContextWithClient(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clientKey{}, name)
+}
+
+//ClientFromContext returns the client identity set by ContextWithClient
+func ClientFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(clientKey{}).(string)
+	return name, ok
+}
+
+//client returns who's making a call with ctx, falling back to the cache's client name
+func (c *memCache) client(ctx context.Context) string {
+	if name, ok := ClientFromContext(ctx); ok {
+		return name
+	}
+
+	return c.opts.clientName
+}
+
+//slowlog is a ring buffer of the most recent slow operations
+type slowlog struct {
+	threshold time.Duration
+	entries   []SlowlogEntry
+	// next is where the next entry goes once entries is full
+	next   int
+	lastID uint64
+	sync.Mutex
+}
+
+func newSlowlog(threshold time.Duration, maxLen int) *slowlog {
+	if maxLen <= 0 {
+		threshold, maxLen = -1, 0
+	}
+
+	return &slowlog{
+		threshold: threshold,
+		entries:   make([]SlowlogEntry, 0, maxLen),
+	}
+}
+
+//log records the operation if it took at least the threshold
+func (sl *slowlog) log(op, key, client string, start time.Time, took time.Duration) {
+	if sl.threshold < 0 || took < sl.threshold {
+		return
+	}
+
+	if len(key) > maxSlowlogKeyLen {
+		key = fmt.Sprintf("%v... (%d more bytes)", key[:maxSlowlogKeyLen], len(key)-maxSlowlogKeyLen)
+	}
+
+	sl.Lock()
+	defer sl.Unlock()
+	sl.lastID++
+	entry := SlowlogEntry{
+		ID:       sl.lastID,
+		Time:     start,
+		Duration: took,
+		Op:       op,
+		Key:      key,
+		Client:   client,
+	}
+
+	if len(sl.entries) < cap(sl.entries) {
+		sl.entries = append(sl.entries, entry)
+		return
+	}
+
+	sl.entries[sl.next] = entry
+	sl.next = (sl.next + 1) % len(sl.entries)
+}
+
+//get returns up to n of the most recent entries, newest first.  n less than or equal to zero returns every entry.
+func (sl *slowlog) get(n int) []SlowlogEntry {
+	sl.Lock()
+	defer sl.Unlock()
+	if n <= 0 || n > len(sl.entries) {
+		n = len(sl.entries)
+	}
+
+	entries := make([]SlowlogEntry, 0, n)
+	if n == 0 {
+		return entries
+	}
+
+	// the newest entry is just before next once the buffer is full, or at the end until it is
+	newest := len(sl.entries) - 1
+	if len(sl.entries) == cap(sl.entries) {
+		newest = (sl.next - 1 + len(sl.entries)) % len(sl.entries)
+	}
+
+	for i := 0; i < n; i++ {
+		entries = append(entries, sl.entries[(newest-i+len(sl.entries))%len(sl.entries)])
+	}
+
+	return entries
+}
+
+func (sl *slowlog) len() int {
+	sl.Lock()
+	defer sl.Unlock()
+	return len(sl.entries)
+}
+
+func (sl *slowlog) reset() {
+	sl.Lock()
+	defer sl.Unlock()
+	sl.entries = sl.entries[:0]
+	sl.next = 0
+}
+
+//Slowlog will return up to n of the most recent operations that took longer than the slowlog's threshold, newest first,
+//like Redis' SLOWLOG GET.  n less than or equal to zero returns every logged operation.
+func (c *memCache) Slowlog(n int) []SlowlogEntry {
+	return c.slowlog.get(n)
+}
+
+//SlowlogLen will return how many slow operations are logged, like Redis' SLOWLOG LEN
+func (c *memCache) SlowlogLen() int {
+	return c.slowlog.len()
+}
+
+//SlowlogReset will clear the slowlog, like Redis' SLOWLOG RESET
+func (c *memCache) SlowlogReset() {
+	c.slowlog.reset()
+}
+
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	return keys[0]
+}
+
+func firstEntryKey(entries []Entry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	return entries[0].Key
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowlog(t *testing.T) {
+	c := NewCache(WithSlowlog(0, 3), WithClientName("test-client")).(StatsCacher)
+	c.Set("Test Key 1", "Test Value", 0)
+	c.Get("Test Key 1")
+	c.MGet("Test Key 2", "Test Key 3")
+	c.Unset(strings.Repeat("k", maxSlowlogKeyLen+10))
+
+	if n := c.SlowlogLen(); n != 3 {
+		t.Fatalf("Expected the slowlog to be bounded to 3 entries but got %v", n)
+	}
+
+	entries := c.Slowlog(0)
+	expected := []struct {
+		op, key string
+	}{
+		{"unset", strings.Repeat("k", maxSlowlogKeyLen) + "... (10 more bytes)"},
+		{"mget", "Test Key 2"},
+		{"get", "Test Key 1"},
+	}
+
+	for i, e := range expected {
+		if entries[i].Op != e.op || entries[i].Key != e.key || entries[i].Client != "test-client" {
+			t.Fatalf("Expected entry %v to be %v %v but got %+v", i, e.op, e.key, entries[i])
+		}
+	}
+
+	if entries[0].ID != 4 || entries[0].Time.IsZero() {
+		t.Fatalf("Got unexpected entry: %+v", entries[0])
+	}
+
+	if entries := c.Slowlog(1); len(entries) != 1 || entries[0].Op != "unset" {
+		t.Fatalf("Expected only the newest entry: %+v", entries)
+	}
+
+	c.SlowlogReset()
+	if n := c.SlowlogLen(); n != 0 {
+		t.Fatalf("Expected the slowlog to be empty after a reset but got %v entries", n)
+	}
+}
+
+func TestSlowlogThreshold(t *testing.T) {
+	tests := []struct {
+		threshold time.Duration
+		maxLen    int
+		expected  int
+	}{
+		{time.Hour, 10, 0},
+		{-1, 10, 0},
+		{0, 0, 0},
+		{0, 10, 2},
+	}
+
+	for _, test := range tests {
+		c := NewCache(WithSlowlog(test.threshold, test.maxLen)).(StatsCacher)
+		c.Set("Test Key", "Test Value", 0)
+		c.Get("Test Key")
+		if n := c.SlowlogLen(); n != test.expected || len(c.Slowlog(0)) != n {
+			t.Fatalf("Expected %v entries with a threshold of %s and length of %v but got %v", test.expected, test.threshold, test.maxLen, n)
+		}
+	}
+}
+
+func TestSlowlogClientFromContext(t *testing.T) {
+	c := NewContextCache(WithSlowlog(0, 10), WithClientName("default-client"))
+	alice := ContextWithClient(context.Background(), "alice")
+	bob, cancel := context.WithCancel(ContextWithClient(context.Background(), "bob"))
+	defer cancel()
+
+	c.SetCtx(alice, "Test Key", "Test Value", 0)
+	c.GetOrLoadCtx(bob, "Test Key", func(ctx context.Context, key string) (string, error) { return "Loaded", nil }, 0)
+	c.MGetCtx(bob, "Test Key")
+	c.GetCtx(context.Background(), "Test Key")
+
+	expected := []struct {
+		op, client string
+	}{
+		{"get", "default-client"},
+		{"mget", "bob"},
+		{"getorload", "bob"},
+		{"set", "alice"},
+	}
+
+	entries := c.(StatsCacher).Slowlog(0)
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected each call to be logged once but got %+v", entries)
+	}
+
+	for i, e := range expected {
+		if entries[i].Op != e.op || entries[i].Client != e.client {
+			t.Fatalf("Expected entry %v to be %v by %v but got %+v", i, e.op, e.client, entries[i])
+		}
+	}
+
+	if name, ok := ClientFromContext(alice); !ok || name != "alice" {
+		t.Fatalf("Expected the context to carry its client but got %q", name)
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	//largestKeys is how many of the largest keys Stats reports
+	largestKeys = 10
+	//nodeOverhead is a rough estimate of the bytes a key costs on top of its key and value, such as the node itself and
+	//its entry in the table
+	nodeOverhead = 64
+)
+
+//StatsCacher is a Cacher that reports on itself, with its Stats and the operations in its slowlog.  Every Cache is a
+//StatsCacher but the admin surface is kept out of Cacher so other implementations don't have to provide it, so callers
+//should type assert for it:
+//
+//	if sc, ok := c.(StatsCacher); ok {
+//		log.Print(sc.Info())
+//	}
+type StatsCacher interface {
+	Cacher
+	Stats() Stats
+	Info() string
+	Slowlog(n int) []SlowlogEntry
+	SlowlogLen() int
+	SlowlogReset()
+}
+
+//Stats describes the state of a cache, like the output of Redis' INFO command.  Stats marshals to JSON and String
+//formats it for humans.
+type Stats struct {
+	//Uptime is how long ago the cache was created
+	Uptime time.Duration `json:"uptime_ns"`
+	//Keys is the number of keys in the cache
+	Keys int `json:"keys"`
+	//KeysWithTTL is the number of keys that will expire
+	KeysWithTTL int `json:"keys_with_ttl"`
+	//AverageTTL is the average time left until keys with a TTL expire
+	AverageTTL time.Duration `json:"average_ttl_ns"`
+	//MemoryByType is an estimate of the bytes used by keys holding each type of value
+	MemoryByType map[string]int64 `json:"memory_by_type"`
+	//LargestKeys are the keys using the most memory, largest first
+	LargestKeys []KeySize `json:"largest_keys"`
+	//Replication describes the cache's role in replication
+	Replication ReplicationInfo `json:"replication"`
+	//Commands is how many times each command has been called
+	Commands map[string]uint64 `json:"commands"`
+}
+
+//KeySize is an estimate of the bytes used by a key
+type KeySize struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+}
+
+//ReplicationInfo describes a cache's role in replication.  Caches don't replicate yet so every cache is a master without
+//any replicas.
+type ReplicationInfo struct {
+	Role              string `json:"role"`
+	ConnectedReplicas int    `json:"connected_replicas"`
+}
+
+//commandStats counts the calls to each command
+type commandStats struct {
+	calls map[string]uint64
+	sync.Mutex
+}
+
+func newCommandStats() *commandStats {
+	return &commandStats{
+		calls: make(map[string]uint64),
+	}
+}
+
+func (cs *commandStats) called(op string) {
+	cs.Lock()
+	defer cs.Unlock()
+	cs.calls[op]++
+}
+
+func (cs *commandStats) snapshot() map[string]uint64 {
+	cs.Lock()
+	defer cs.Unlock()
+	calls := make(map[string]uint64, len(cs.calls))
+	for op, n := range cs.calls {
+		calls[op] = n
+	}
+
+	return calls
+}
+
+//Stats will return a description of the cache's keys, memory use and commands.  The keys are scanned in batches, like
+//Scan, so the description is only approximate while the cache is being changed.
+func (c *memCache) Stats() Stats {
+	ks := c.keyspace()
+	stats := Stats{
+		Uptime:       c.opts.clock.Now().Sub(c.started),
+		MemoryByType: make(map[string]int64),
+		LargestKeys:  make([]KeySize, 0, largestKeys),
+		Replication: ReplicationInfo{
+			Role: "master",
+		},
+		Commands: c.commands.snapshot(),
+	}
+
+	stats.KeysWithTTL, stats.AverageTTL = ks.ttlRegistry.averageTTL()
+
+	cursor := ""
+	for {
+		next, results, err := ks.table.Scan(cursor, rangeBatch)
+		if err != nil {
+			break
+		}
+
+		for _, r := range results {
+			size := KeySize{
+				Key:   r.n.key,
+				Type:  r.n.valueType(),
+				Bytes: int64(len(r.n.key) + len(r.n.value) + nodeOverhead),
+			}
+
+			stats.Keys++
+			stats.MemoryByType[size.Type] += size.Bytes
+			stats.LargestKeys = addLargest(stats.LargestKeys, size)
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	return stats
+}
+
+//addLargest adds size to largest if it's one of the largest keys, keeping largest sorted with the largest first
+func addLargest(largest []KeySize, size KeySize) []KeySize {
+	i := sort.Search(len(largest), func(i int) bool {
+		return largest[i].Bytes < size.Bytes
+	})
+
+	if i == largestKeys {
+		return largest
+	}
+
+	if len(largest) < largestKeys {
+		largest = append(largest, KeySize{})
+	}
+
+	copy(largest[i+1:], largest[i:])
+	largest[i] = size
+	return largest
+}
+
+//Info will return the cache's Stats formatted for humans, like Redis' INFO command
+func (c *memCache) Info() string {
+	return c.Stats().String()
+}
+
+//String formats the Stats in sections of field:value lines, like Redis' INFO command
+func (s Stats) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Server\r\n")
+	fmt.Fprintf(&b, "uptime_in_seconds:%d\r\n", int64(s.Uptime.Seconds()))
+	fmt.Fprintf(&b, "\r\n# Replication\r\n")
+	fmt.Fprintf(&b, "role:%v\r\n", s.Replication.Role)
+	fmt.Fprintf(&b, "connected_slaves:%d\r\n", s.Replication.ConnectedReplicas)
+	fmt.Fprintf(&b, "\r\n# Memory\r\n")
+	for _, typ := range sortedKeys(s.MemoryByType) {
+		fmt.Fprintf(&b, "used_memory_%v:%d\r\n", typ, s.MemoryByType[typ])
+	}
+	for i, size := range s.LargestKeys {
+		fmt.Fprintf(&b, "largest_key_%d:key=%q,type=%v,bytes=%d\r\n", i, size.Key, size.Type, size.Bytes)
+	}
+	fmt.Fprintf(&b, "\r\n# Commandstats\r\n")
+	commands := make([]string, 0, len(s.Commands))
+	for op := range s.Commands {
+		commands = append(commands, op)
+	}
+	sort.Strings(commands)
+	for _, op := range commands {
+		fmt.Fprintf(&b, "cmdstat_%v:calls=%d\r\n", op, s.Commands[op])
+	}
+	fmt.Fprintf(&b, "\r\n# Keyspace\r\n")
+	fmt.Fprintf(&b, "keys:%d\r\n", s.Keys)
+	fmt.Fprintf(&b, "expires:%d\r\n", s.KeysWithTTL)
+	fmt.Fprintf(&b, "avg_ttl:%d\r\n", int64(s.AverageTTL/time.Millisecond))
+	return b.String()
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	c := NewCache().(StatsCacher)
+	c.Set("Small Key", "v", 0)
+	c.Set("Large Key", strings.Repeat("v", 1000), 5*time.Minute)
+	c.Set("Medium Key", strings.Repeat("v", 100), 10*time.Minute)
+	c.Get("Small Key")
+	c.Get("Garbage Key")
+
+	stats := c.Stats()
+	if stats.Keys != 3 || stats.KeysWithTTL != 2 {
+		t.Fatalf("Got unexpected key counts: %+v", stats)
+	}
+
+	if stats.AverageTTL < 7*time.Minute || stats.AverageTTL > 8*time.Minute {
+		t.Fatalf("Expected an average TTL of about 7.5 minutes but got %s", stats.AverageTTL)
+	}
+
+	expectedMemory := int64(len("Small Key") + 1 + len("Large Key") + 1000 + len("Medium Key") + 100 + 3*nodeOverhead)
+	if stats.MemoryByType[StringType] != expectedMemory {
+		t.Fatalf("Expected %v bytes of strings but got %v", expectedMemory, stats.MemoryByType)
+	}
+
+	expectedOrder := []string{"Large Key", "Medium Key", "Small Key"}
+	for i, key := range expectedOrder {
+		if stats.LargestKeys[i].Key != key {
+			t.Fatalf("Expected %v to be the #%v largest key: %+v", key, i, stats.LargestKeys)
+		}
+	}
+
+	if stats.Commands["set"] != 3 || stats.Commands["get"] != 2 {
+		t.Fatalf("Got unexpected command counts: %v", stats.Commands)
+	}
+
+	if stats.Replication.Role != "master" || stats.Replication.ConnectedReplicas != 0 {
+		t.Fatalf("Got unexpected replication info: %+v", stats.Replication)
+	}
+
+	var decoded Stats
+	encoded, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Couldn't marshal stats: %+v", err)
+	}
+
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Keys != 3 || decoded.Commands["set"] != 3 {
+		t.Fatalf("Stats didn't survive a round trip through JSON: %s %+v", encoded, err)
+	}
+
+	info := c.Info()
+	for _, line := range []string{"role:master\r\n", "keys:3\r\n", "expires:2\r\n", "cmdstat_set:calls=3\r\n"} {
+		if !strings.Contains(info, line) {
+			t.Fatalf("Expected info to contain %q:\n%v", line, info)
+		}
+	}
+}
+
+func TestLargestKeys(t *testing.T) {
+	var largest []KeySize
+	for i := 0; i < largestKeys*2; i++ {
+		largest = addLargest(largest, KeySize{Bytes: int64(i)})
+	}
+
+	if len(largest) != largestKeys {
+		t.Fatalf("Expected %v largest keys but got %v", largestKeys, len(largest))
+	}
+
+	for i, size := range largest {
+		if expected := int64(largestKeys*2 - 1 - i); size.Bytes != expected {
+			t.Fatalf("Expected key #%v to be %v bytes but got %v", i, expected, size.Bytes)
+		}
+	}
+}
+
+func TestStatsCacher(t *testing.T) {
+	caches := map[string]interface{}{
+		"NewCache":        NewCache(),
+		"ordered":         NewCache(WithOrderedKeys()),
+		"NewContextCache": NewContextCache(),
+		"namespace":       NewNamespaces().Select("a"),
+	}
+
+	for name, c := range caches {
+		if _, ok := c.(StatsCacher); !ok {
+			t.Fatalf("Expected a cache from %v to be a StatsCacher", name)
+		}
+
+		if _, ok := c.(io.Closer); !ok {
+			t.Fatalf("Expected a cache from %v to be an io.Closer", name)
+		}
+	}
+}
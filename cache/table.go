@@ -2,8 +2,9 @@ package cache
 
 import (
 	"fmt"
+	"hash/fnv"
+	"strconv"
 	"sync"
-	"time"
 )
 
 //HashTable is the interface that defines what a table needs to do to be used as a hash table in the Cacher interface
@@ -11,6 +12,22 @@ type HashTable interface {
 	Set(key, value string) Result
 	Unset(key string) Result
 	Get(key string) Result
+	//Atomic calls fn while holding the table's lock.  The table passed to fn must only be used inside of fn and lets fn
+	//make several changes to the table without anyone else seeing or changing the table part way through.
+	Atomic(fn func(t HashTable))
+	//Scan returns a batch of roughly count keys starting at cursor along with the cursor for the next batch.  An empty
+	//cursor starts a new scan and an empty next cursor means the scan is complete.  Every key that exists for the whole
+	//scan is returned exactly once, while keys set or unset during the scan may or may not be returned.
+	Scan(cursor string, count int) (next string, results []Result, err error)
+	//Len returns the number of keys in the table
+	Len() int
+}
+
+//ErrInvalidCursor is returned when a scan is continued from a cursor the table didn't hand out
+type ErrInvalidCursor string
+
+func (e ErrInvalidCursor) Error() string {
+	return fmt.Sprintf("Invalid scan cursor: %v", string(e))
 }
 
 //ErrKeyNotFound is returned when a requested key could not be found in the table
@@ -20,43 +37,136 @@ func (e ErrKeyNotFound) Error() string {
 	return fmt.Sprintf("Could not find key: %v", string(e))
 }
 
+// tableSlots is the number of maps the table's keys are spread across.  Scans walk the table a slot at a time and use the
+// slot as their cursor, since a key always lives in the same slot no matter what else is added or removed.
+const tableSlots = 1024
+
+const defaultScanCount = 10
+
 type mapHashTable struct {
-	m map[string]*node
+	slots [tableSlots]map[string]*node
+	count int
+	clock Clock
 	sync.RWMutex
 }
 
-func newTable() HashTable {
-	return &mapHashTable{
-		m: make(map[string]*node),
+func newTable(clock Clock) HashTable {
+	t := &mapHashTable{clock: clock}
+	for i := range t.slots {
+		t.slots[i] = make(map[string]*node)
 	}
+
+	return t
+}
+
+func slotFor(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % tableSlots)
 }
 
 func (t *mapHashTable) Set(key, value string) Result {
 	t.Lock()
 	defer t.Unlock()
+	return t.set(key, value)
+}
+
+func (t *mapHashTable) Unset(key string) Result {
+	t.Lock()
+	defer t.Unlock()
+	return t.unset(key)
+}
+
+func (t *mapHashTable) Get(key string) Result {
+	t.Lock()
+	defer t.Unlock()
+	return t.get(key)
+}
+
+func (t *mapHashTable) Atomic(fn func(t HashTable)) {
+	t.Lock()
+	defer t.Unlock()
+	fn(lockedMapHashTable{t})
+}
 
-	n, ok := t.m[key]
+func (t *mapHashTable) Len() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.count
+}
+
+func (t *mapHashTable) Scan(cursor string, count int) (string, []Result, error) {
+	return t.scan(cursor, count, false)
+}
+
+//scan walks the table a slot at a time.  Unless the caller already holds the lock it's only held for a slot at a time
+//so a scan never blocks writers for long.
+func (t *mapHashTable) scan(cursor string, count int, locked bool) (string, []Result, error) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	slot := 0
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil || parsed >= tableSlots {
+			return "", nil, ErrInvalidCursor(cursor)
+		}
+		slot = int(parsed)
+	}
+
+	results := make([]Result, 0, count)
+	for ; slot < tableSlots && len(results) < count; slot++ {
+		if !locked {
+			t.RLock()
+		}
+
+		for _, n := range t.slots[slot] {
+			results = append(results, Result{
+				Action: Retrieved,
+				n:      *n,
+			})
+		}
+
+		if !locked {
+			t.RUnlock()
+		}
+	}
+
+	if slot == tableSlots {
+		return "", results, nil
+	}
+
+	return strconv.Itoa(slot), results, nil
+}
+
+func (t *mapHashTable) set(key, value string) Result {
+	m := t.slots[slotFor(key)]
+	n, ok := m[key]
 	if ok {
+		prev := *n
 		n.value = value
-		n.created = time.Now().UTC()
+		n.created = t.clock.Now().UTC()
+		n.version = nextVersion()
 		return Result{
 			n:      *n,
+			prev:   &prev,
 			Action: Updated,
 		}
 	}
 
-	n = newRecord(key, value)
-	t.m[key] = n
+	n = newRecord(key, value, t.clock.Now().UTC())
+	m[key] = n
+	t.count++
 	return Result{
 		n:      *n,
 		Action: Created,
 	}
 }
 
-func (t *mapHashTable) Unset(key string) Result {
-	t.Lock()
-	defer t.Unlock()
-	n, exists := t.m[key]
+func (t *mapHashTable) unset(key string) Result {
+	m := t.slots[slotFor(key)]
+	n, exists := m[key]
 	if !exists {
 		return Result{
 			Action: Failed,
@@ -64,18 +174,16 @@ func (t *mapHashTable) Unset(key string) Result {
 		}
 	}
 
-	delete(t.m, key)
+	delete(m, key)
+	t.count--
 	return Result{
 		Action: Deleted,
 		n:      *n,
 	}
 }
 
-func (t *mapHashTable) Get(key string) Result {
-	t.Lock()
-	defer t.Unlock()
-
-	n, exists := t.m[key]
+func (t *mapHashTable) get(key string) Result {
+	n, exists := t.slots[slotFor(key)][key]
 	if !exists {
 		return Result{
 			Action: Failed,
@@ -88,3 +196,22 @@ func (t *mapHashTable) Get(key string) Result {
 		n:      *n,
 	}
 }
+
+//lockedMapHashTable is handed to Atomic's callback and uses the table without locking since the lock is already held
+type lockedMapHashTable struct {
+	t *mapHashTable
+}
+
+func (l lockedMapHashTable) Set(key, value string) Result { return l.t.set(key, value) }
+
+func (l lockedMapHashTable) Unset(key string) Result { return l.t.unset(key) }
+
+func (l lockedMapHashTable) Get(key string) Result { return l.t.get(key) }
+
+func (l lockedMapHashTable) Atomic(fn func(t HashTable)) { fn(l) }
+
+func (l lockedMapHashTable) Scan(cursor string, count int) (string, []Result, error) {
+	return l.t.scan(cursor, count, true)
+}
+
+func (l lockedMapHashTable) Len() int { return l.t.count }
@@ -8,7 +8,7 @@ import (
 func TestSetKey(t *testing.T) {
 	testKey := "Test"
 	expectVal := "Value"
-	table := newTable()
+	table := newTable(systemClock{})
 
 	r := table.Set(testKey, expectVal)
 	if r.Err != nil {
@@ -19,7 +19,7 @@ func TestSetKey(t *testing.T) {
 func TestGetKey(t *testing.T) {
 	testKey := "Test"
 	expectedVal := "Value 123"
-	table := newTable()
+	table := newTable(systemClock{})
 
 	r := table.Set(testKey, expectedVal)
 	if r.Err != nil {
@@ -45,7 +45,7 @@ func TestGetKey(t *testing.T) {
 func TestUnsetKey(t *testing.T) {
 	testKey := "Test"
 	testVal := "Val"
-	table := newTable()
+	table := newTable(systemClock{})
 
 	r := table.Set(testKey, testVal)
 	if r.Err != nil {
@@ -64,7 +64,7 @@ func TestUnsetKey(t *testing.T) {
 }
 
 func BenchmarkSet(b *testing.B) {
-	table := newTable()
+	table := newTable(systemClock{})
 	fmt.Println("benching")
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -75,3 +75,28 @@ func BenchmarkSet(b *testing.B) {
 		}
 	}
 }
+
+func TestAtomic(t *testing.T) {
+	table := newTable(systemClock{})
+	table.Set("Test", "1")
+
+	done := make(chan struct{})
+	table.Atomic(func(locked HashTable) {
+		go func() {
+			// this has to wait for the atomic callback to finish
+			table.Set("Test", "3")
+			close(done)
+		}()
+
+		r := locked.Get("Test")
+		locked.Set("Test", r.GetValue()+"2")
+		if r := locked.Get("Test"); r.GetValue() != "12" {
+			t.Fatalf("Got unexpected value inside of Atomic: %v", r)
+		}
+	})
+
+	<-done
+	if r := table.Get("Test"); r.GetValue() != "3" {
+		t.Fatalf("Got unexpected value after Atomic: %v", r)
+	}
+}
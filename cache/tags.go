@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+//tagIndex maps tags to the keys tagged with them and keys back to their tags so a key's tags can be cleaned up when it's
+//unset.  It's always changed while holding the table's lock, so it's always locked after the table.
+type tagIndex struct {
+	keysByTag map[string]map[string]struct{}
+	tagsByKey map[string][]string
+	sync.RWMutex
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		keysByTag: make(map[string]map[string]struct{}),
+		tagsByKey: make(map[string][]string),
+	}
+}
+
+//set replaces the tags of key with tags
+func (ti *tagIndex) set(key string, tags []string) {
+	ti.Lock()
+	defer ti.Unlock()
+	ti.removeLocked(key)
+	if len(tags) == 0 {
+		return
+	}
+
+	for _, tag := range tags {
+		keys, ok := ti.keysByTag[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			ti.keysByTag[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	ti.tagsByKey[key] = append([]string(nil), tags...)
+}
+
+//remove removes key from every tag it has
+func (ti *tagIndex) remove(key string) {
+	ti.Lock()
+	defer ti.Unlock()
+	ti.removeLocked(key)
+}
+
+func (ti *tagIndex) removeLocked(key string) {
+	for _, tag := range ti.tagsByKey[key] {
+		keys := ti.keysByTag[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(ti.keysByTag, tag)
+		}
+	}
+
+	delete(ti.tagsByKey, key)
+}
+
+//of returns the tags of key
+func (ti *tagIndex) of(key string) []string {
+	ti.RLock()
+	defer ti.RUnlock()
+	return append([]string(nil), ti.tagsByKey[key]...)
+}
+
+//keys returns every key tagged with tag
+func (ti *tagIndex) keys(tag string) []string {
+	ti.RLock()
+	defer ti.RUnlock()
+	keys := make([]string, 0, len(ti.keysByTag[tag]))
+	for key := range ti.keysByTag[tag] {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (ti *tagIndex) clear() {
+	ti.Lock()
+	defer ti.Unlock()
+	ti.keysByTag = make(map[string]map[string]struct{})
+	ti.tagsByKey = make(map[string][]string)
+}
+
+//InvalidateTag will atomically unset every key tagged with tag, see Tags, and return the Results of unsetting them.
+func (c *memCache) InvalidateTag(tag string) []Result {
+	defer c.observe("invalidatetag", tag, time.Now())
+	ks := c.keyspace()
+	var results []Result
+	ks.table.Atomic(func(t HashTable) {
+		keys := ks.tags.keys(tag)
+		results = make([]Result, 0, len(keys))
+		for _, key := range keys {
+			results = append(results, ks.unset(t, key))
+		}
+	})
+
+	c.evictedResults(results)
+	return results
+}
+
+//Tagged will return every key tagged with tag, in no particular order.
+func (c *memCache) Tagged(tag string) []string {
+	return c.keyspace().tags.keys(tag)
+}
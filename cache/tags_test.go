@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	removed := make(map[string]EvictReason)
+	c := NewCache(OnEvict(func(key, value string, reason EvictReason) {
+		removed[key] = reason
+	}))
+
+	c.Set("page:1", "Page 1", 0, Tags("product:1"))
+	c.Set("page:2", "Page 2", 5*time.Minute, Tags("product:1", "product:2"))
+	c.Set("page:3", "Page 3", 0, Tags("product:2"))
+	c.Set("page:4", "Page 4", 0)
+
+	results := c.InvalidateTag("product:1")
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 keys to be invalidated but got %v", results)
+	}
+
+	for _, r := range results {
+		if r.Action != Deleted || removed[r.GetKey()] != Removed {
+			t.Fatalf("Expected %v to be deleted and reported as removed: %v", r.GetKey(), r)
+		}
+	}
+
+	if found := c.Exists("page:1", "page:2", "page:3", "page:4"); found != 2 {
+		t.Fatalf("Expected only the tagged keys to be unset but %v keys exist", found)
+	}
+
+	if tagged := c.Tagged("product:2"); len(tagged) != 1 || tagged[0] != "page:3" {
+		t.Fatalf("Expected page:2 to be removed from its other tags: %v", tagged)
+	}
+
+	if results := c.InvalidateTag("Garbage Tag"); len(results) != 0 {
+		t.Fatalf("Expected an unknown tag to invalidate nothing: %v", results)
+	}
+}
+
+func TestTagsAreCleanedUp(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock))
+	c.Set("Test Key 1", "Test Value", 0, Tags("tag"))
+	c.Set("Test Key 2", "Test Value", 50*time.Millisecond, Tags("tag"))
+	c.Set("Test Key 3", "Test Value", 0, Tags("tag"))
+	c.Set("Test Key 4", "Test Value", 0, Tags("tag"))
+
+	// setting a key again replaces its tags
+	c.Set("Test Key 3", "Test Value", 0, Tags("other"))
+	c.Unset("Test Key 1")
+	c.Rename("Test Key 4", "Test Key 5")
+	clock.Advance(100 * time.Millisecond)
+
+	tagged := c.Tagged("tag")
+	sort.Strings(tagged)
+	if len(tagged) != 1 || tagged[0] != "Test Key 5" {
+		t.Fatalf("Expected only the renamed key to be left with the tag: %v", tagged)
+	}
+
+	c.Flush()
+	if tagged := c.Tagged("other"); len(tagged) != 0 {
+		t.Fatalf("Expected flushing to clear the tags: %v", tagged)
+	}
+}
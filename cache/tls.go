@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+//ErrNoCertificates is returned when a CA file doesn't hold any PEM certificates
+type ErrNoCertificates string
+
+func (e ErrNoCertificates) Error() string {
+	return fmt.Sprintf("Couldn't find any certificates in %v", string(e))
+}
+
+//CertReloader serves a certificate and key from files, reloading them when either file changes so certificates can be
+//renewed without a restart.  The files are checked at most once per handshake and if the new files can't be loaded the
+//old certificate keeps being served.
+type CertReloader struct {
+	certFile, keyFile string
+	cert              *tls.Certificate
+	certMod, keyMod   time.Time
+	sync.RWMutex
+}
+
+//NewCertReloader returns a CertReloader serving the PEM certificate and key in certFile and keyFile.  It fails if they
+//can't be loaded now, rather than at the first handshake.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+//Reload will load the certificate and key from their files now, whether or not they've changed
+func (r *CertReloader) Reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.cert = &cert
+	r.certMod, r.keyMod = certMod, keyMod
+	return nil
+}
+
+func (r *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+//certificate returns the current certificate, reloading it first if its files have changed
+func (r *CertReloader) certificate() *tls.Certificate {
+	certMod, keyMod, err := r.modTimes()
+	r.RLock()
+	cert, changed := r.cert, err == nil && (!certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod))
+	r.RUnlock()
+
+	if changed {
+		// a renewal may have written only one of the files so far, keep serving the old certificate until both load
+		if err := r.Reload(); err == nil {
+			r.RLock()
+			cert = r.cert
+			r.RUnlock()
+		}
+	}
+
+	return cert
+}
+
+//GetCertificate returns the current certificate, it's meant for tls.Config's GetCertificate on servers
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+//GetClientCertificate returns the current certificate, it's meant for tls.Config's GetClientCertificate on clients
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+//loadCertPool returns a pool of the PEM certificates in path
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, ErrNoCertificates(path)
+	}
+
+	return pool, nil
+}
+
+//ServerTLSConfig returns a TLS config for listeners serving certs.  If clientCAFile isn't empty clients must present a
+//certificate signed by one of the CAs in it, which AuthenticateTLS can map to an ACL user.
+func ServerTLSConfig(certs *CertReloader, clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pool, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+//ClientTLSConfig returns a TLS config for clients that trust the CAs in caFile, or the system's CAs if caFile is empty.
+//If certs isn't nil it's presented to servers that ask for a client certificate.
+func ClientTLSConfig(caFile string, certs *CertReloader) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	if certs != nil {
+		config.GetClientCertificate = certs.GetClientCertificate
+	}
+
+	return config, nil
+}
+
+//AuthenticateTLS returns the ACL user a connection's verified client certificate belongs to, the user named by the
+//certificate's common name.  It fails with ErrAuthFailed if the client didn't present a verified certificate or its user
+//doesn't exist or is disabled, like a wrong password would.
+func (a *ACL) AuthenticateTLS(state tls.ConnectionState) (string, error) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", ErrAuthFailed("client certificate")
+	}
+
+	name := state.VerifiedChains[0][0].Subject.CommonName
+	a.RLock()
+	u, ok := a.users[name]
+	a.RUnlock()
+
+	if name == "" || !ok || !u.Enabled {
+		return "", ErrAuthFailed(name)
+	}
+
+	return name, nil
+}
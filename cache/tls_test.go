@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//testCA signs certificates generated at test time
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	ca := &testCA{dir: t.TempDir()}
+	ca.cert, ca.key = ca.sign(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+	ca.write(t, "ca.pem", "CERTIFICATE", ca.cert.Raw)
+	return ca
+}
+
+//sign will generate a key and certificate from template, signed by parent or self-signed if parent is nil
+func (ca *testCA) sign(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Couldn't generate key: %+v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("Couldn't generate serial: %+v", err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Couldn't create certificate: %+v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Couldn't parse certificate: %+v", err)
+	}
+
+	return cert, key
+}
+
+//issue will write a certificate for name signed by the CA to name.pem and name-key.pem, returning the certificate
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) *x509.Certificate {
+	cert, key := ca.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		DNSNames:    []string{name},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+	}, ca.cert, ca.key)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Couldn't marshal key: %+v", err)
+	}
+
+	ca.write(t, name+".pem", "CERTIFICATE", cert.Raw)
+	ca.write(t, name+"-key.pem", "EC PRIVATE KEY", der)
+	return cert
+}
+
+func (ca *testCA) write(t *testing.T, file, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := os.WriteFile(ca.path(file), data, 0600); err != nil {
+		t.Fatalf("Couldn't write %v: %+v", file, err)
+	}
+}
+
+func (ca *testCA) path(file string) string {
+	return filepath.Join(ca.dir, file)
+}
+
+func (ca *testCA) reloader(t *testing.T, name string) *CertReloader {
+	r, err := NewCertReloader(ca.path(name+".pem"), ca.path(name+"-key.pem"))
+	if err != nil {
+		t.Fatalf("Couldn't load %v's certificate: %+v", name, err)
+	}
+
+	return r
+}
+
+//handshake connects client to a listener using server and returns both sides' view of the connection
+func handshake(t *testing.T, server, client *tls.Config) (tls.ConnectionState, tls.ConnectionState, error) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatalf("Couldn't listen: %+v", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan tls.ConnectionState, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		defer conn.Close()
+
+		tc := conn.(*tls.Conn)
+		if tc.Handshake() != nil {
+			close(accepted)
+			return
+		}
+
+		accepted <- tc.ConnectionState()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		<-accepted
+		return tls.ConnectionState{}, tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	// with TLS 1.3 a rejected client certificate is only reported on the client's first read
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	conn.Read(make([]byte, 1))
+	serverState, ok := <-accepted
+	if !ok {
+		return tls.ConnectionState{}, tls.ConnectionState{}, errors.New("the server failed the handshake")
+	}
+
+	return serverState, conn.ConnectionState(), nil
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	server, err := ServerTLSConfig(ca.reloader(t, "127.0.0.1"), "")
+	if err != nil {
+		t.Fatalf("Couldn't create server config: %+v", err)
+	}
+
+	client, err := ClientTLSConfig(ca.path("ca.pem"), nil)
+	if err != nil {
+		t.Fatalf("Couldn't create client config: %+v", err)
+	}
+
+	_, state, err := handshake(t, server, client)
+	if err != nil || !state.HandshakeComplete {
+		t.Fatalf("Couldn't connect over TLS: %+v", err)
+	}
+
+	// a client that doesn't trust the CA must refuse the server
+	untrusted, _ := ClientTLSConfig("", nil)
+	if _, _, err := handshake(t, server, untrusted); err == nil {
+		t.Fatalf("Client trusted a server signed by an unknown CA")
+	}
+
+	if _, err := ClientTLSConfig(ca.path("127.0.0.1-key.pem"), nil); err == nil {
+		t.Fatalf("Expected a CA file without certificates to fail")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	ca.issue(t, "alice", x509.ExtKeyUsageClientAuth)
+	ca.issue(t, "mallory", x509.ExtKeyUsageClientAuth)
+
+	server, err := ServerTLSConfig(ca.reloader(t, "127.0.0.1"), ca.path("ca.pem"))
+	if err != nil {
+		t.Fatalf("Couldn't create server config: %+v", err)
+	}
+
+	acl := NewACL()
+	acl.SetUser(User{Name: "alice", Enabled: true, Categories: ReadCategory, KeyPatterns: []string{"*"}})
+
+	tests := []struct {
+		client   string
+		user     string
+		connects bool
+	}{
+		{"alice", "alice", true},
+		{"mallory", "", true},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		var certs *CertReloader
+		if test.client != "" {
+			certs = ca.reloader(t, test.client)
+		}
+
+		client, err := ClientTLSConfig(ca.path("ca.pem"), certs)
+		if err != nil {
+			t.Fatalf("Couldn't create client config: %+v", err)
+		}
+
+		state, _, err := handshake(t, server, client)
+		if (err == nil) != test.connects {
+			t.Fatalf("Expected %q connecting to be %v but got %+v", test.client, test.connects, err)
+		}
+
+		if !test.connects {
+			continue
+		}
+
+		user, err := acl.AuthenticateTLS(state)
+		if user != test.user {
+			t.Fatalf("Expected %v's certificate to authenticate as %q but got %q %+v", test.client, test.user, user, err)
+		}
+
+		if _, failed := err.(ErrAuthFailed); test.user == "" && !failed {
+			t.Fatalf("Expected %v to fail to authenticate with ErrAuthFailed but got %+v", test.client, err)
+		}
+	}
+}
+
+func TestCertReload(t *testing.T) {
+	ca := newTestCA(t)
+	first := ca.issue(t, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	certs := ca.reloader(t, "127.0.0.1")
+	server, _ := ServerTLSConfig(certs, "")
+	client, _ := ClientTLSConfig(ca.path("ca.pem"), nil)
+
+	_, state, err := handshake(t, server, client)
+	if err != nil || state.PeerCertificates[0].SerialNumber.Cmp(first.SerialNumber) != 0 {
+		t.Fatalf("Expected the first certificate to be served: %+v", err)
+	}
+
+	// renew the certificate and make sure the files look changed even on filesystems with coarse timestamps
+	second := ca.issue(t, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{"127.0.0.1.pem", "127.0.0.1-key.pem"} {
+		if err := os.Chtimes(ca.path(file), later, later); err != nil {
+			t.Fatalf("Couldn't touch %v: %+v", file, err)
+		}
+	}
+
+	_, state, err = handshake(t, server, client)
+	if err != nil || state.PeerCertificates[0].SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Fatalf("Expected the renewed certificate to be served without a restart: %+v", err)
+	}
+
+	// a half written renewal keeps the old certificate
+	ca.write(t, "127.0.0.1-key.pem", "EC PRIVATE KEY", []byte("garbage"))
+	_, state, err = handshake(t, server, client)
+	if err != nil || state.PeerCertificates[0].SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Fatalf("Expected a broken renewal to keep the last good certificate: %+v", err)
+	}
+}
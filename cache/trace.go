@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	//AttrOperation is the span attribute holding the name of the operation, such as set
+	AttrOperation = "cache.operation"
+	//AttrKeyHash is the span attribute holding a hash of the key, so traces don't leak keys
+	AttrKeyHash = "cache.key_hash"
+	//AttrAction is the span attribute holding the Action of the operation's Result
+	AttrAction = "cache.action"
+	//AttrErrorType is the span attribute holding the type of the error the operation failed with
+	AttrErrorType = "error.type"
+)
+
+//Tracer starts a span for every cache operation called with a context, see ContextCacher.  It's modelled after
+//OpenTelemetry's tracer so it can be adapted to one without the cache depending on it.
+type Tracer interface {
+	//Start starts a span named name as a child of any span in ctx and returns a context holding the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+//Span is a single traced operation
+type Span interface {
+	SetAttribute(key string, value string)
+	End()
+}
+
+//WithTracer starts a span with t for every operation called with a context
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+//NoopTracer is a Tracer that doesn't record anything.  It's used when a cache isn't given a Tracer.
+type NoopTracer struct{}
+
+//Start returns ctx and a Span that does nothing
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value string) {}
+
+func (noopSpan) End() {}
+
+//RecordedSpan is a span recorded by a RecordingTracer
+type RecordedSpan struct {
+	//ID identifies the span, starting at 1 for the first span recorded
+	ID         int
+	Name       string
+	Attributes map[string]string
+	Start      time.Time
+	End        time.Time
+	//ParentID is the ID of the span in the context the span was started with, or 0 if there wasn't one
+	ParentID int
+}
+
+//RecordingTracer is a Tracer that keeps every span it starts in memory, which is useful for tests
+type RecordingTracer struct {
+	spans []*RecordedSpan
+	sync.Mutex
+}
+
+//NewRecordingTracer returns a newly instantiated RecordingTracer that's ready to use
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+type recordedSpanKey struct{}
+
+//Start records a new span as a child of any span the RecordingTracer started in ctx
+func (rt *RecordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parentID, _ := ctx.Value(recordedSpanKey{}).(int)
+	rt.Lock()
+	span := &RecordedSpan{
+		ID:         len(rt.spans) + 1,
+		Name:       name,
+		Attributes: make(map[string]string),
+		Start:      time.Now(),
+		ParentID:   parentID,
+	}
+	rt.spans = append(rt.spans, span)
+	rt.Unlock()
+	return context.WithValue(ctx, recordedSpanKey{}, span.ID), recordingSpan{rt, span}
+}
+
+//Spans returns a copy of every span that has ended, in the order they were started
+func (rt *RecordingTracer) Spans() []RecordedSpan {
+	rt.Lock()
+	defer rt.Unlock()
+	spans := make([]RecordedSpan, 0, len(rt.spans))
+	for _, span := range rt.spans {
+		if span.End.IsZero() {
+			continue
+		}
+
+		attrs := make(map[string]string, len(span.Attributes))
+		for k, v := range span.Attributes {
+			attrs[k] = v
+		}
+
+		copied := *span
+		copied.Attributes = attrs
+		spans = append(spans, copied)
+	}
+
+	return spans
+}
+
+//Reset forgets every recorded span
+func (rt *RecordingTracer) Reset() {
+	rt.Lock()
+	defer rt.Unlock()
+	rt.spans = nil
+}
+
+type recordingSpan struct {
+	rt   *RecordingTracer
+	span *RecordedSpan
+}
+
+func (s recordingSpan) SetAttribute(key string, value string) {
+	s.rt.Lock()
+	defer s.rt.Unlock()
+	s.span.Attributes[key] = value
+}
+
+func (s recordingSpan) End() {
+	s.rt.Lock()
+	defer s.rt.Unlock()
+	if s.span.End.IsZero() {
+		s.span.End = time.Now()
+	}
+}
+
+//hashKey hashes key for span attributes so traces can tell keys apart without holding them
+func hashKey(key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+//startSpan starts a span for op on key
+func (c *memCache) startSpan(ctx context.Context, op, key string) (context.Context, Span) {
+	ctx, span := c.opts.tracer.Start(ctx, "cache."+op)
+	span.SetAttribute(AttrOperation, op)
+	if key != "" {
+		span.SetAttribute(AttrKeyHash, hashKey(key))
+	}
+
+	return ctx, span
+}
+
+//endSpan records the outcome of an operation returning r on span and ends it
+func endSpan(span Span, r Result) {
+	span.SetAttribute(AttrAction, r.Action.String())
+	endSpanErr(span, r.Err)
+}
+
+//endSpanErr records the error an operation without a single Result failed with on span and ends it
+func endSpanErr(span Span, err error) {
+	if err != nil {
+		span.SetAttribute(AttrErrorType, fmt.Sprintf("%T", err))
+	}
+
+	span.End()
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTracing(t *testing.T) {
+	tracer := NewRecordingTracer()
+	c := NewContextCache(WithTracer(tracer))
+	ctx, parent := tracer.Start(context.Background(), "request")
+
+	c.SetCtx(ctx, "Test Key", "Test Value", 0)
+	c.GetCtx(ctx, "Test Key")
+	c.GetCtx(ctx, "Garbage Key")
+	c.MGetCtx(ctx, "Test Key", "Garbage Key")
+	parent.End()
+
+	spans := tracer.Spans()
+	if len(spans) != 5 {
+		t.Fatalf("Expected 5 spans but got %v: %+v", len(spans), spans)
+	}
+
+	tests := []struct {
+		name, op, action, errType string
+	}{
+		{"cache.set", "set", Created.String(), ""},
+		{"cache.get", "get", Retrieved.String(), ""},
+		{"cache.get", "get", Failed.String(), fmt.Sprintf("%T", ErrKeyNotFound(""))},
+		{"cache.mget", "mget", "", ""},
+	}
+
+	for i, test := range tests {
+		span := spans[i+1]
+		if span.Name != test.name || span.Attributes[AttrOperation] != test.op || span.ParentID != spans[0].ID {
+			t.Fatalf("Expected a %v span under the request span but got %+v", test.name, span)
+		}
+
+		if span.Attributes[AttrAction] != test.action || span.Attributes[AttrErrorType] != test.errType {
+			t.Fatalf("Expected span %v to have action %q and error type %q but got %v", i, test.action, test.errType, span.Attributes)
+		}
+
+		if span.End.Before(span.Start) {
+			t.Fatalf("Span %v ended before it started: %+v", i, span)
+		}
+	}
+
+	if hash := spans[1].Attributes[AttrKeyHash]; hash != hashKey("Test Key") || hash == "Test Key" {
+		t.Fatalf("Expected the key to be hashed but got %v", hash)
+	}
+
+	tracer.Reset()
+	if spans := tracer.Spans(); len(spans) != 0 {
+		t.Fatalf("Expected no spans after a reset but got %+v", spans)
+	}
+}
+
+func TestNoopTracer(t *testing.T) {
+	c := NewContextCache()
+	if r := c.SetCtx(context.Background(), "Test Key", "Test Value", time.Minute); r.Err != nil {
+		t.Fatalf("Couldn't set a key without a tracer: %+v", r.Err)
+	}
+
+	if r := c.GetCtx(context.Background(), "Test Key"); r.GetValue() != "Test Value" {
+		t.Fatalf("Got unexpected result without a tracer: %v", r)
+	}
+}
@@ -26,21 +26,48 @@ type ttlInfo struct {
 	key    string
 	expire time.Time
 	ix     int
+
+	// soft is the time after which the key is considered stale but is still served.  It's zero if the key has no soft TTL
+	soft    time.Time
+	softTTL time.Duration
+	hardTTL time.Duration
+
+	// sliding keys have their expire time pushed back by hardTTL every time they're accessed
+	sliding bool
+}
+
+//TTLInfo describes the TTL registered for a key
+type TTLInfo struct {
+	//Remaining is how long until the key expires
+	Remaining time.Duration
+	//SoftTTL is how long after being set the key becomes stale, or zero if it has no soft TTL
+	SoftTTL time.Duration
+	//Sliding is true if the key's TTL is reset every time it's accessed
+	Sliding bool
+	//IdleTimeout is how long a sliding key can go without being accessed before it expires
+	IdleTimeout time.Duration
 }
 
 type ttlRegistry struct {
 	ttlByKey      map[string]*ttlInfo
 	queue         ttlQueue
 	table         HashTable
-	nextTTLExpire *time.Timer
+	clock         Clock
+	nextTTLExpire Timer
+	onExpire      func(n node)
+	//onUnset is called with every node expired out of the table while still holding the table and registry locks
+	onUnset func(n node)
+	//stopped is set once the registry has been stopped, after which it never starts its timer again
+	stopped bool
 	sync.RWMutex
 }
 
-func newTTLRegistry(table HashTable) *ttlRegistry {
+func newTTLRegistry(table HashTable, clock Clock) *ttlRegistry {
 	reg := &ttlRegistry{
 		ttlByKey: make(map[string]*ttlInfo),
 		queue:    make(ttlQueue, 0),
 		table:    table,
+		clock:    clock,
 	}
 
 	heap.Init(&reg.queue)
@@ -54,29 +81,99 @@ func (reg *ttlRegistry) RegisterTTL(key string, created time.Time, ttl time.Dura
 
 	reg.Lock()
 	defer reg.Unlock()
+	ti := reg.register(key, created.Add(ttl).UTC())
+	ti.soft = time.Time{}
+	ti.softTTL = 0
+	ti.hardTTL = ttl
+	ti.sliding = false
+	return nil
+}
+
+//RegisterSoftTTL registers a TTL for key that is considered stale once soft has elapsed and expires once hard has elapsed
+func (reg *ttlRegistry) RegisterSoftTTL(key string, created time.Time, soft, hard time.Duration) error {
+	if hard <= 0 {
+		return ErrInvalidTTL(hard)
+	}
+
+	if soft <= 0 || soft >= hard {
+		return ErrInvalidTTL(soft)
+	}
+
+	reg.Lock()
+	defer reg.Unlock()
+	ti := reg.register(key, created.Add(hard).UTC())
+	ti.soft = created.Add(soft).UTC()
+	ti.softTTL = soft
+	ti.hardTTL = hard
+	ti.sliding = false
+	return nil
+}
+
+//RegisterExpireAt registers an absolute time at which key expires.  A time in the past expires the key when the timer next
+//fires, which happens on another goroutine, so callers that need the key gone right away should unset it themselves.
+func (reg *ttlRegistry) RegisterExpireAt(key string, at time.Time) error {
+	if at.IsZero() {
+		return ErrInvalidTTL(0)
+	}
+
+	reg.Lock()
+	defer reg.Unlock()
+	ti := reg.register(key, at.UTC())
+	ti.soft = time.Time{}
+	ti.softTTL = 0
+	ti.hardTTL = 0
+	ti.sliding = false
+	return nil
+}
+
+//RegisterSlidingTTL registers a TTL for key that expires once it has gone idle, without being touched, for idle
+func (reg *ttlRegistry) RegisterSlidingTTL(key string, accessed time.Time, idle time.Duration) error {
+	if idle <= 0 {
+		return ErrInvalidTTL(idle)
+	}
+
+	reg.Lock()
+	defer reg.Unlock()
+	ti := reg.register(key, accessed.Add(idle).UTC())
+	ti.soft = time.Time{}
+	ti.softTTL = 0
+	ti.hardTTL = idle
+	ti.sliding = true
+	return nil
+}
+
+//Touch pushes back the expire time of key if it has a sliding TTL
+func (reg *ttlRegistry) Touch(key string, accessed time.Time) {
+	reg.RLock()
+	ti, ok := reg.ttlByKey[key]
+	sliding := ok && ti.sliding
+	reg.RUnlock()
+	if !sliding {
+		return
+	}
+
+	reg.Lock()
+	defer reg.Unlock()
+
+	// the key could have expired or been changed since we checked it
+	if ti, ok = reg.ttlByKey[key]; ok && ti.sliding {
+		reg.register(key, accessed.Add(ti.hardTTL).UTC())
+	}
+}
+
+//register adds or updates the expire time for key in the queue and makes sure the timer will fire in time for it.  The
+//caller must hold the registry's lock.
+func (reg *ttlRegistry) register(key string, expire time.Time) *ttlInfo {
 	var ti *ttlInfo
 	var exists bool
 
 	if ti, exists = reg.ttlByKey[key]; !exists {
 		ti = &ttlInfo{
 			key:    key,
-			expire: created.Add(ttl).UTC(),
+			expire: expire,
 		}
 	} else if exists {
-		ti.expire = created.Add(ttl).UTC()
-	}
-
-	// peek the next ttl, if it's after the one we're adding reset the timer to our newly added ttl
-	for reg.queue.Len() > 0 {
-		next := reg.queue[0]
-		if next.expire.After(ti.expire) {
-			if reg.nextTTLExpire != nil {
-				reg.nextTTLExpire.Stop()
-			}
-			reg.nextTTLExpire = time.AfterFunc(ti.expire.Sub(time.Now().UTC()), reg.expireKeys)
-		}
-
-		break
+		ti.expire = expire
 	}
 
 	if !exists {
@@ -86,7 +183,47 @@ func (reg *ttlRegistry) RegisterTTL(key string, created time.Time, ttl time.Dura
 		heap.Fix(&reg.queue, ti.ix)
 	}
 
-	return nil
+	// if our ttl is now the next to expire reset the timer to it
+	if reg.queue[0] == ti {
+		reg.schedule(ti.expire.Sub(reg.clock.Now().UTC()))
+	}
+
+	return ti
+}
+
+//schedule resets the timer to expire keys after d unless the registry has been stopped.  The caller must hold the
+//registry's lock.
+func (reg *ttlRegistry) schedule(d time.Duration) {
+	if reg.nextTTLExpire != nil {
+		reg.nextTTLExpire.Stop()
+	}
+
+	if reg.stopped {
+		reg.nextTTLExpire = nil
+		return
+	}
+
+	reg.nextTTLExpire = reg.clock.AfterFunc(d, reg.expireKeys)
+}
+
+//Stop stops the timer for good so keys are never expired again
+func (reg *ttlRegistry) Stop() {
+	reg.Lock()
+	defer reg.Unlock()
+	reg.stopped = true
+	reg.schedule(0)
+}
+
+//Stale returns whether key has outlived its soft TTL, along with the soft and hard TTLs it was registered with
+func (reg *ttlRegistry) Stale(key string) (stale bool, soft, hard time.Duration) {
+	reg.RLock()
+	defer reg.RUnlock()
+	ti, ok := reg.ttlByKey[key]
+	if !ok || ti.soft.IsZero() {
+		return false, 0, 0
+	}
+
+	return !ti.soft.After(reg.clock.Now().UTC()), ti.softTTL, ti.hardTTL
 }
 
 func (reg *ttlRegistry) GetTTL(key string) (time.Duration, error) {
@@ -97,7 +234,53 @@ func (reg *ttlRegistry) GetTTL(key string) (time.Duration, error) {
 		return time.Duration(0), ErrTTLNotFound(key)
 	}
 
-	return ti.expire.Sub(time.Now().UTC()), nil
+	return ti.expire.Sub(reg.clock.Now().UTC()), nil
+}
+
+//GetTTLInfo returns a description of the TTL registered for key
+func (reg *ttlRegistry) GetTTLInfo(key string) (TTLInfo, error) {
+	reg.RLock()
+	defer reg.RUnlock()
+	ti, ok := reg.ttlByKey[key]
+	if !ok {
+		return TTLInfo{}, ErrTTLNotFound(key)
+	}
+
+	info := TTLInfo{
+		Remaining: ti.expire.Sub(reg.clock.Now().UTC()),
+		SoftTTL:   ti.softTTL,
+		Sliding:   ti.sliding,
+	}
+
+	if ti.sliding {
+		info.IdleTimeout = ti.hardTTL
+	}
+
+	return info, nil
+}
+
+//Len returns the number of keys with a TTL
+func (reg *ttlRegistry) Len() int {
+	reg.RLock()
+	defer reg.RUnlock()
+	return reg.queue.Len()
+}
+
+//averageTTL returns the number of keys with a TTL and the average time left until they expire
+func (reg *ttlRegistry) averageTTL() (int, time.Duration) {
+	reg.RLock()
+	defer reg.RUnlock()
+	if len(reg.queue) == 0 {
+		return 0, 0
+	}
+
+	now := reg.clock.Now().UTC()
+	var total time.Duration
+	for _, ti := range reg.queue {
+		total += ti.expire.Sub(now)
+	}
+
+	return len(reg.queue), total / time.Duration(len(reg.queue))
 }
 
 func (reg *ttlRegistry) UnregisterTTL(key string) error {
@@ -115,30 +298,105 @@ func (reg *ttlRegistry) UnregisterTTL(key string) error {
 	return nil
 }
 
-func (reg *ttlRegistry) expireKeys() {
+//Lookup returns a copy of the TTL registered for key so it can be attached to another key
+func (reg *ttlRegistry) Lookup(key string) (ttlInfo, bool) {
+	reg.RLock()
+	defer reg.RUnlock()
+	ti, exists := reg.ttlByKey[key]
+	if !exists {
+		return ttlInfo{}, false
+	}
+
+	return *ti, true
+}
+
+//Detach unregisters the TTL for key and returns it so it can be attached to the key somewhere else
+func (reg *ttlRegistry) Detach(key string) (ttlInfo, bool) {
 	reg.Lock()
 	defer reg.Unlock()
-	now := time.Now().UTC()
-
-	for reg.queue.Len() > 0 {
-		// peek the next to make sure we should expire
-		next := reg.queue[0]
-		if next.expire.After(now) {
-			if reg.nextTTLExpire != nil {
-				reg.nextTTLExpire.Stop()
-			}
-			reg.nextTTLExpire = time.AfterFunc(next.expire.Sub(now), reg.expireKeys)
+
+	ti, exists := reg.ttlByKey[key]
+	if !exists {
+		return ttlInfo{}, false
+	}
+
+	heap.Remove(&reg.queue, ti.ix)
+	delete(reg.ttlByKey, key)
+	return *ti, true
+}
+
+//Attach registers a TTL previously returned by Detach for key, keeping its expire time and kind
+func (reg *ttlRegistry) Attach(key string, detached ttlInfo) {
+	reg.Lock()
+	defer reg.Unlock()
+	ti := reg.register(key, detached.expire)
+	ti.soft = detached.soft
+	ti.softTTL = detached.softTTL
+	ti.hardTTL = detached.hardTTL
+	ti.sliding = detached.sliding
+}
+
+//Clear unregisters every TTL and stops the timer
+func (reg *ttlRegistry) Clear() {
+	reg.Lock()
+	defer reg.Unlock()
+	if reg.nextTTLExpire != nil {
+		reg.nextTTLExpire.Stop()
+		reg.nextTTLExpire = nil
+	}
+
+	reg.ttlByKey = make(map[string]*ttlInfo)
+	reg.queue = make(ttlQueue, 0)
+}
+
+func (reg *ttlRegistry) expireKeys() {
+	expired := reg.popExpired()
+	if reg.onExpire == nil {
+		return
+	}
+
+	// the hook is called after the registry lock is released so it's free to call back into the cache
+	for _, n := range expired {
+		reg.onExpire(n)
+	}
+}
+
+func (reg *ttlRegistry) popExpired() []node {
+	expired := make([]node, 0)
+
+	// the table is always locked before the registry, so hold the table's lock while we expire keys out of it
+	reg.table.Atomic(func(table HashTable) {
+		reg.Lock()
+		defer reg.Unlock()
+		if reg.stopped {
 			return
 		}
 
-		r := reg.table.Unset(next.key)
-		if _, ok := r.Err.(ErrKeyNotFound); r.Err != nil && !ok {
-			log.Printf("Couldn't unset key while expiring key %v: %+v", next.key, r.Err)
+		now := reg.clock.Now().UTC()
+		for reg.queue.Len() > 0 {
+			// peek the next to make sure we should expire
+			next := reg.queue[0]
+			if next.expire.After(now) {
+				reg.schedule(next.expire.Sub(now))
+				return
+			}
+
+			r := table.Unset(next.key)
+			if _, ok := r.Err.(ErrKeyNotFound); r.Err != nil && !ok {
+				log.Printf("Couldn't unset key while expiring key %v: %+v", next.key, r.Err)
+			} else if r.Err == nil {
+				expired = append(expired, r.n)
+				if reg.onUnset != nil {
+					reg.onUnset(r.n)
+				}
+			}
+
+			heap.Pop(&reg.queue)
+			delete(reg.ttlByKey, next.key)
 		}
+	})
 
-		heap.Pop(&reg.queue)
-		delete(reg.ttlByKey, next.key)
-	}
+	return expired
 }
 
 type ttlQueue []*ttlInfo
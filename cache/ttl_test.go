@@ -6,9 +6,10 @@ import (
 	"time"
 )
 
-func getTestRegistry() *ttlRegistry {
-	table := newTable()
-	return newTTLRegistry(table)
+func getTestRegistry() (*ttlRegistry, *FakeClock) {
+	clock := NewFakeClock(time.Now())
+	table := newTable(clock)
+	return newTTLRegistry(table, clock), clock
 }
 
 func TestTTLRegistrationAndHeapTest(t *testing.T) {
@@ -28,8 +29,8 @@ func TestTTLRegistrationAndHeapTest(t *testing.T) {
 		},
 	}
 
-	reg := getTestRegistry()
-	now := time.Now().UTC()
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
 
 	if err := reg.RegisterTTL(testCases[0].Key, now, testCases[0].TTL); err != nil {
 		t.Fatalf("Experienced error when registering ttl: %+v", err)
@@ -124,8 +125,8 @@ func TestExpiration(t *testing.T) {
 		},
 	}
 
-	reg := getTestRegistry()
-	now := time.Now().UTC()
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
 
 	for _, tc := range testCases {
 		t.Run(tc.Key, func(t *testing.T) {
@@ -143,38 +144,45 @@ func TestExpiration(t *testing.T) {
 		})
 	}
 
-	for range time.Tick(750 * time.Millisecond) {
-		finished := true
-		now := time.Now().UTC()
+	// step the clock past every TTL, checking each key has expired exactly when it should have
+	for step := 0; step < 10; step++ {
+		clock.Advance(250 * time.Millisecond)
+		now := clock.Now().UTC()
 		for _, tc := range testCases {
-			reg.RLock()
-			r := reg.table.Get(tc.Key)
+			var r Result
+			var ti *ttlInfo
+			var tiExists bool
+			reg.table.Atomic(func(table HashTable) {
+				reg.RLock()
+				defer reg.RUnlock()
+				r = table.Get(tc.Key)
+				ti, tiExists = reg.ttlByKey[tc.Key]
+			})
 			_, isKeyNotFoundErr := r.Err.(ErrKeyNotFound)
-			ti, tiExists := reg.ttlByKey[tc.Key]
 
 			// if this key shouldn't expire it shouldn't be in the ttlByKey since it'd have been unregistered
 			if tc.NeverExpire && (tiExists || r.Action != Retrieved || r.GetValue() != tc.Value || r.GetKey() != tc.Key) {
 				t.Fatalf("Key that shouldn't have expired had something bad happen: Key: %v ; Result: %v ; ttl info: %v", tc.Key, r, ti)
 			}
 
-			// if this key has expired in the last iteration mark it as such
-			if tc.Expire.Before(now) {
-				tc.Expired = true
+			if tc.NeverExpire {
+				continue
 			}
 
+			tc.Expired = !tc.Expire.After(now)
 			if tc.Expired && (r.Action != Failed || !isKeyNotFoundErr || r.Err == nil || tiExists) {
 				t.Fatalf("Key that should have expired was still found: Key %v ; Result: %v; ttl info: %v", tc.Key, r, ti)
 			}
 
-			// if we still have a case that hasn't expired yet set finished to false
-			if !tc.NeverExpire && !tc.Expired {
-				finished = false
+			if !tc.Expired && (r.Err != nil || !tiExists) {
+				t.Fatalf("Key expired before its TTL elapsed: Key %v ; Result: %v; Now: %s", tc.Key, r, now)
 			}
-			reg.RUnlock()
 		}
+	}
 
-		if finished {
-			break
+	for _, tc := range testCases {
+		if !tc.NeverExpire && !tc.Expired {
+			t.Fatalf("Key %v never expired", tc.Key)
 		}
 	}
 }
@@ -196,8 +204,8 @@ func TestGetTTL(t *testing.T) {
 		},
 	}
 
-	reg := getTestRegistry()
-	now := time.Now().UTC()
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
 	for _, tc := range testCases {
 		t.Run(tc.Key, func(t *testing.T) {
 			if err := reg.RegisterTTL(tc.Key, now, tc.TTL); err != nil {
@@ -213,8 +221,7 @@ func TestGetTTL(t *testing.T) {
 				t.Fatalf("Couldn't get TTL for key %v: %+v", tc.Key, err)
 			}
 
-			// some time may have dropped off the TTL since we added it (should be close to negligible)
-			if ttl > tc.TTL || ttl < (tc.TTL-marginOfError) {
+			if ttl != tc.TTL {
 				t.Fatalf("Got an unexpected TTL for key %v, got %s, expected %s", tc.Key, ttl, tc.TTL)
 			}
 		})
@@ -244,8 +251,8 @@ func TestUnregisterTTL(t *testing.T) {
 		},
 	}
 
-	reg := getTestRegistry()
-	now := time.Now().UTC()
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
 	for _, tc := range testCases {
 		t.Run(tc.Key, func(t *testing.T) {
 			if err := reg.RegisterTTL(tc.Key, now, tc.TTL); err != nil {
@@ -279,3 +286,62 @@ func TestUnregisterTTL(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterSoftTTL(t *testing.T) {
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
+
+	if err := reg.RegisterSoftTTL("Test", now, 5*time.Second, 5*time.Second); err == nil {
+		t.Fatalf("Expected an error registering a soft ttl that isn't shorter than the hard ttl")
+	}
+
+	if err := reg.RegisterSoftTTL("Test", now, -1*time.Second, 5*time.Second); err == nil {
+		t.Fatalf("Expected an error registering a negative soft ttl")
+	}
+
+	if err := reg.RegisterSoftTTL("Test", now.Add(-2*time.Second), 1*time.Second, 5*time.Second); err != nil {
+		t.Fatalf("Couldn't register soft ttl: %+v", err)
+	}
+
+	if stale, soft, hard := reg.Stale("Test"); !stale || soft != 1*time.Second || hard != 5*time.Second {
+		t.Fatalf("Expected key to be stale: Stale: %v Soft: %s Hard: %s", stale, soft, hard)
+	}
+
+	// registering a plain ttl should clear the soft ttl
+	if err := reg.RegisterTTL("Test", now, 5*time.Second); err != nil {
+		t.Fatalf("Couldn't register ttl: %+v", err)
+	}
+
+	if stale, _, _ := reg.Stale("Test"); stale {
+		t.Fatalf("Expected key to no longer be stale after registering a ttl without a soft ttl")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	reg, clock := getTestRegistry()
+	now := clock.Now().UTC()
+
+	if err := reg.RegisterSlidingTTL("Sliding", now, 5*time.Second); err != nil {
+		t.Fatalf("Couldn't register sliding ttl: %+v", err)
+	}
+
+	if err := reg.RegisterTTL("Fixed", now, 5*time.Second); err != nil {
+		t.Fatalf("Couldn't register ttl: %+v", err)
+	}
+
+	later := now.Add(3 * time.Second)
+	reg.Touch("Sliding", later)
+	reg.Touch("Fixed", later)
+
+	if expire := reg.ttlByKey["Sliding"].expire; !expire.Equal(later.Add(5 * time.Second)) {
+		t.Fatalf("Expected touching a sliding key to push back its expire time, got %s", expire)
+	}
+
+	if expire := reg.ttlByKey["Fixed"].expire; !expire.Equal(now.Add(5 * time.Second)) {
+		t.Fatalf("Expected touching a fixed key to leave its expire time alone, got %s", expire)
+	}
+
+	if reg.queue[0].key != "Fixed" {
+		t.Fatalf("Queue has %v as first key, expected Fixed", reg.queue[0].key)
+	}
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//ErrTxAborted is returned by Exec when a watched key changed after it was watched.  None of the queued commands are applied.
+type ErrTxAborted string
+
+func (e ErrTxAborted) Error() string {
+	return fmt.Sprintf("Transaction aborted, watched key %v was changed", string(e))
+}
+
+type txCommand func(ks *keyspace, t HashTable) Result
+
+//watchIndex remembers the last version of watched keys that were removed.  A key that didn't exist when it was watched
+//has the same version when it doesn't exist at Exec, so this is how a transaction finds out it was set and removed again
+//in the meantime.  It's always changed while holding the table's lock, so it's always locked after the table.
+type watchIndex struct {
+	watchers  map[string]int
+	removedAt map[string]uint64
+	sync.Mutex
+}
+
+func newWatchIndex() *watchIndex {
+	return &watchIndex{
+		watchers:  make(map[string]int),
+		removedAt: make(map[string]uint64),
+	}
+}
+
+func (wi *watchIndex) watch(key string) {
+	wi.Lock()
+	defer wi.Unlock()
+	wi.watchers[key]++
+}
+
+func (wi *watchIndex) unwatch(key string) {
+	wi.Lock()
+	defer wi.Unlock()
+	if wi.watchers[key]--; wi.watchers[key] <= 0 {
+		delete(wi.watchers, key)
+		delete(wi.removedAt, key)
+	}
+}
+
+//removed records the version of n if its key is being watched
+func (wi *watchIndex) removed(n node) {
+	wi.Lock()
+	defer wi.Unlock()
+	if wi.watchers[n.key] > 0 && n.version > wi.removedAt[n.key] {
+		wi.removedAt[n.key] = n.version
+	}
+}
+
+//removedSince reports whether key was set after version was given out and has been removed since
+func (wi *watchIndex) removedSince(key string, version uint64) bool {
+	wi.Lock()
+	defer wi.Unlock()
+	return wi.removedAt[key] > version
+}
+
+//watch is a watched key's version and the last version given out to any key when it was watched
+type watch struct {
+	version uint64
+	since   uint64
+}
+
+//Tx queues up commands to be applied to the cache atomically when Exec is called, similar to Redis' MULTI and EXEC.  Keys
+//can be watched so the transaction is aborted if any of them change before Exec.  A Tx that watched keys should always be
+//finished with Exec or Discard.  A Tx isn't safe for concurrent use.
+type Tx struct {
+	c       *memCache
+	ks      *keyspace
+	watched map[string]watch
+	cmds    []txCommand
+	err     error
+}
+
+//Multi starts a new transaction.
+func (c *memCache) Multi() *Tx {
+	return &Tx{
+		c:       c,
+		watched: make(map[string]watch),
+	}
+}
+
+//Watch remembers the current version of each key.  Exec will abort the transaction if any of them have been set, unset
+//or expired in the meantime, even if a key that didn't exist was set and then unset again.  Watching a key that's
+//already watched does nothing.
+func (tx *Tx) Watch(keys ...string) {
+	if tx.ks == nil {
+		tx.ks = tx.c.keyspace()
+	}
+
+	ks := tx.ks
+	ks.table.Atomic(func(t HashTable) {
+		for _, key := range keys {
+			if _, ok := tx.watched[key]; ok {
+				continue
+			}
+
+			ks.watches.watch(key)
+			tx.watched[key] = watch{
+				version: t.Get(key).n.version,
+				since:   atomic.LoadUint64(&lastVersion),
+			}
+		}
+	})
+}
+
+//Set queues setting a key, see Cacher's Set.
+func (tx *Tx) Set(key, value string, ttl time.Duration, opts ...SetOption) {
+	so := newSetOptions(opts)
+	if err := so.validate(ttl); err != nil && tx.err == nil {
+		tx.err = err
+	}
+
+	tx.cmds = append(tx.cmds, func(ks *keyspace, t HashTable) Result {
+		return ks.set(t, key, value, ttl, so)
+	})
+}
+
+//Unset queues unsetting a key, see Cacher's Unset.
+func (tx *Tx) Unset(key string) {
+	tx.cmds = append(tx.cmds, func(ks *keyspace, t HashTable) Result {
+		return ks.unset(t, key)
+	})
+}
+
+//SetTTL queues setting a key's TTL, see Cacher's SetTTL.
+func (tx *Tx) SetTTL(key string, ttl time.Duration) {
+	tx.cmds = append(tx.cmds, func(ks *keyspace, t HashTable) Result {
+		return ks.changeTTL(t, key, func(n node) error {
+			return ks.ttlRegistry.RegisterTTL(key, n.created, ttl)
+		})
+	})
+}
+
+//Get queues retrieving a key, see Cacher's Get.
+func (tx *Tx) Get(key string) {
+	tx.cmds = append(tx.cmds, func(ks *keyspace, t HashTable) Result {
+		return tx.c.get(ks, t, key, tx.c.loader)
+	})
+}
+
+//Exec applies all of the queued commands atomically and returns their Results in the order they were queued.  If a
+//queued command had invalid options or a watched key changed nothing is applied and an error is returned instead.  The
+//Tx is reset afterwards and can be used for another transaction.
+func (tx *Tx) Exec() ([]Result, error) {
+	defer tx.c.observe("exec", "", time.Now())
+	defer tx.Discard()
+	if tx.err != nil {
+		return nil, tx.err
+	}
+
+	var results []Result
+	var err error
+	ks := tx.c.keyspace()
+	if ks.closed {
+		return nil, ErrClosed("exec")
+	}
+
+	ks.table.Atomic(func(t HashTable) {
+		for key, w := range tx.watched {
+			// the keyspace being swapped out changes every key in it
+			if ks != tx.ks || checkVersion(t, key, w.version) != nil || ks.watches.removedSince(key, w.since) {
+				err = ErrTxAborted(key)
+				return
+			}
+		}
+
+		results = make([]Result, 0, len(tx.cmds))
+		for _, cmd := range tx.cmds {
+			results = append(results, cmd(ks, t))
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx.c.evictedResults(results)
+	return results, nil
+}
+
+//Discard throws away the queued commands and watched keys.
+func (tx *Tx) Discard() {
+	for key := range tx.watched {
+		tx.ks.watches.unwatch(key)
+	}
+
+	tx.ks = nil
+	tx.watched = make(map[string]watch)
+	tx.cmds = nil
+	tx.err = nil
+}
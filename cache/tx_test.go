@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxExec(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 1", "Test Value 1", 0)
+
+	tx := c.Multi()
+	tx.Set("Test Key 2", "Test Value 2", 0)
+	tx.SetTTL("Test Key 1", 5*time.Minute)
+	tx.Get("Test Key 2")
+	tx.Unset("Test Key 1")
+	tx.Get("Test Key 1")
+
+	// nothing should be applied until Exec
+	if r := c.Get("Test Key 2"); r.Err == nil {
+		t.Fatalf("Queued set was applied before Exec: %v", r)
+	}
+
+	results, err := tx.Exec()
+	if err != nil {
+		t.Fatalf("Couldn't exec transaction: %+v", err)
+	}
+
+	expected := []action{Created, Updated, Retrieved, Deleted, Failed}
+	if len(results) != len(expected) {
+		t.Fatalf("Got %v results, expected %v", len(results), len(expected))
+	}
+
+	for i, r := range results {
+		if r.Action != expected[i] {
+			t.Fatalf("Got unexpected action for command %v: Actual: %v Expected: %v", i, r.Action, expected[i])
+		}
+	}
+
+	if results[2].GetValue() != "Test Value 2" {
+		t.Fatalf("Queued get didn't see the queued set: %v", results[2])
+	}
+
+	if ttl, err := c.GetTTL("Test Key 1"); ttl != NoKey || err == nil {
+		t.Fatalf("Expected unset key's TTL to be unregistered: Err: %+v TTL: %s", err, ttl)
+	}
+}
+
+func TestTxWatch(t *testing.T) {
+	c := NewCache()
+	c.Set("Test Key 1", "Test Value 1", 0)
+
+	tx := c.Multi()
+	tx.Watch("Test Key 1", "Test Key 2")
+	tx.Set("Test Key 1", "Test Value 2", 0)
+
+	// changing a watched key should abort the transaction
+	c.Set("Test Key 2", "Someone Else", 0)
+	results, err := tx.Exec()
+	if _, ok := err.(ErrTxAborted); !ok || results != nil {
+		t.Fatalf("Expected ErrTxAborted after a watched key changed: Results: %v Err: %+v", results, err)
+	}
+
+	if r := c.Get("Test Key 1"); r.GetValue() != "Test Value 1" {
+		t.Fatalf("Aborted transaction changed a key: %v", r)
+	}
+
+	// the transaction is reset after Exec so it can be retried
+	tx.Watch("Test Key 1", "Test Key 2")
+	tx.Set("Test Key 1", "Test Value 2", 0)
+	if _, err := tx.Exec(); err != nil {
+		t.Fatalf("Couldn't exec transaction with unchanged watched keys: %+v", err)
+	}
+
+	if r := c.Get("Test Key 1"); r.GetValue() != "Test Value 2" {
+		t.Fatalf("Transaction didn't set key: %v", r)
+	}
+}
+
+func TestTxInvalidCommand(t *testing.T) {
+	c := NewCache()
+	tx := c.Multi()
+	tx.Set("Test Key 1", "Test Value 1", 0)
+	tx.Set("Test Key 2", "Test Value 2", 0, IfAbsent(), IfPresent())
+	if _, err := tx.Exec(); err == nil {
+		t.Fatalf("Expected an error executing a transaction with invalid options")
+	}
+
+	if r := c.Get("Test Key 1"); r.Err == nil {
+		t.Fatalf("Transaction with an invalid command set a key: %v", r)
+	}
+}
+
+func TestTxWatchMissingKey(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := NewCache(WithClock(clock))
+	tests := []struct {
+		name   string
+		change func(key string)
+	}{
+		{"unset", func(key string) {
+			c.Set(key, "Someone Else", 0)
+			c.Unset(key)
+		}},
+		{"expired", func(key string) {
+			c.Set(key, "Someone Else", 1*time.Second)
+			clock.Advance(2 * time.Second)
+		}},
+		{"flushed", func(key string) {
+			c.Set(key, "Someone Else", 0)
+			c.Flush()
+		}},
+		{"renamed", func(key string) {
+			c.Set(key, "Someone Else", 0)
+			c.Rename(key, key+" Renamed")
+		}},
+	}
+
+	for _, test := range tests {
+		tx := c.Multi()
+		tx.Watch(test.name)
+		tx.Set(test.name, "Test Value", 0)
+		test.change(test.name)
+		if _, err := tx.Exec(); err != ErrTxAborted(test.name) {
+			t.Fatalf("Expected a watched missing key that was %v to abort the transaction but got %+v", test.name, err)
+		}
+
+		// a key that's still missing and wasn't touched doesn't abort anything
+		tx.Watch(test.name)
+		tx.Set(test.name, "Test Value", 0)
+		if _, err := tx.Exec(); err != nil {
+			t.Fatalf("Couldn't exec transaction watching an untouched missing key: %+v", err)
+		}
+	}
+
+	if watchers := len(c.(*memCache).keyspace().watches.watchers); watchers != 0 {
+		t.Fatalf("Expected finished transactions to stop watching their keys but %d are still watched", watchers)
+	}
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+//Codec turns values into the strings a Cacher stores and back again
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+//JSONCodec stores values as JSON
+type JSONCodec struct{}
+
+//Marshal encodes v as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+//Unmarshal decodes JSON in data into v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+//GobCodec stores values with encoding/gob
+type GobCodec struct{}
+
+//Marshal encodes v with gob
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+//Unmarshal decodes gob in data into v
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+//BinaryCodec stores values that encode themselves, such as generated protobuf messages, by implementing
+//encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+type BinaryCodec struct{}
+
+//ErrNotBinary is returned by BinaryCodec for values that don't implement encoding.BinaryMarshaler or
+//encoding.BinaryUnmarshaler
+type ErrNotBinary string
+
+func (e ErrNotBinary) Error() string {
+	return fmt.Sprintf("%v doesn't implement the binary marshaling interfaces", string(e))
+}
+
+//Marshal encodes v with its MarshalBinary method
+func (BinaryCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, ErrNotBinary(fmt.Sprintf("%T", v))
+	}
+
+	return m.MarshalBinary()
+}
+
+//Unmarshal decodes data into v with its UnmarshalBinary method
+func (BinaryCodec) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return ErrNotBinary(fmt.Sprintf("%T", v))
+	}
+
+	return u.UnmarshalBinary(data)
+}
+
+//ErrEncode is returned when a value couldn't be encoded for a key
+type ErrEncode struct {
+	Key string
+	Err error
+}
+
+func (e ErrEncode) Error() string {
+	return fmt.Sprintf("Couldn't encode value for key %v: %+v", e.Key, e.Err)
+}
+
+//ErrDecode is returned when a key exists but its value couldn't be decoded, such as when it was set with a different
+//Codec or type
+type ErrDecode struct {
+	Key string
+	Err error
+}
+
+func (e ErrDecode) Error() string {
+	return fmt.Sprintf("Couldn't decode value of key %v: %+v", e.Key, e.Err)
+}
+
+//Typed stores values of type V in a Cacher, encoding them with a Codec.  V can be a pointer, as is usual for types such
+//as protobuf messages.
+type Typed[V any] struct {
+	c     Cacher
+	codec Codec
+	//elem is the type V points to, or nil if V isn't a pointer
+	elem reflect.Type
+}
+
+//NewTyped returns a Typed that stores values in c encoded with codec
+func NewTyped[V any](c Cacher, codec Codec) *Typed[V] {
+	t := &Typed[V]{
+		c:     c,
+		codec: codec,
+	}
+
+	if typ := reflect.TypeOf((*V)(nil)).Elem(); typ.Kind() == reflect.Ptr {
+		t.elem = typ.Elem()
+	}
+
+	return t
+}
+
+//Set will encode value and set it, see Cacher's Set.  The Result fails with ErrEncode if value couldn't be encoded.
+func (t *Typed[V]) Set(key string, value V, ttl time.Duration, opts ...SetOption) Result {
+	encoded, err := t.encode(key, value)
+	if err != nil {
+		return Result{
+			Action: Failed,
+			Err:    err,
+		}
+	}
+
+	return t.c.Set(key, encoded, ttl, opts...)
+}
+
+//Get will retrieve and decode the value of key, see Cacher's Get.  The Result fails with ErrDecode if key exists but its
+//value couldn't be decoded, and the returned value is only meaningful if the Result didn't fail.
+func (t *Typed[V]) Get(key string) (V, Result) {
+	return t.decode(t.c.Get(key))
+}
+
+//GetOrLoad will retrieve and decode the value of key or load and set it, see Cacher's GetOrLoad.
+func (t *Typed[V]) GetOrLoad(key string, loader func(key string) (V, error), ttl time.Duration, opts ...SetOption) (V, Result) {
+	return t.decode(t.c.GetOrLoad(key, func(key string) (string, error) {
+		value, err := loader(key)
+		if err != nil {
+			return "", err
+		}
+
+		return t.encode(key, value)
+	}, ttl, opts...))
+}
+
+//MGet will retrieve and decode several keys at once, see Cacher's MGet
+func (t *Typed[V]) MGet(keys ...string) ([]V, []Result) {
+	results := t.c.MGet(keys...)
+	values := make([]V, len(results))
+	for i, r := range results {
+		values[i], results[i] = t.decode(r)
+	}
+
+	return values, results
+}
+
+//Unset will unset key, see Cacher's Unset
+func (t *Typed[V]) Unset(key string) Result {
+	return t.c.Unset(key)
+}
+
+func (t *Typed[V]) encode(key string, value V) (string, error) {
+	// pointers are passed to the codec so BinaryCodec finds methods with pointer receivers, every other codec is happy
+	// with them too.  A V that's already a pointer is passed as it is, BinaryCodec won't find any methods on a pointer to
+	// a pointer.
+	var v interface{} = &value
+	if t.elem != nil && !reflect.ValueOf(value).IsNil() {
+		v = value
+	}
+
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return "", ErrEncode{Key: key, Err: err}
+	}
+
+	return string(data), nil
+}
+
+func (t *Typed[V]) decode(r Result) (V, Result) {
+	var value V
+	if r.Err != nil {
+		return value, r
+	}
+
+	// like encode a V that's already a pointer is passed as it is, pointing at a new value to decode into
+	var v interface{} = &value
+	if t.elem != nil {
+		value = reflect.New(t.elem).Interface().(V)
+		v = value
+	}
+
+	if err := t.codec.Unmarshal([]byte(r.GetValue()), v); err != nil {
+		return value, Result{
+			Action: Failed,
+			n:      r.n,
+			Err:    ErrDecode{Key: r.GetKey(), Err: err},
+		}
+	}
+
+	return value, r
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testProduct struct {
+	ID    int
+	Name  string
+	Price float64
+}
+
+//testPoint encodes itself like a generated protobuf message would
+type testPoint struct {
+	X, Y uint32
+}
+
+func (p *testPoint) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, p.X)
+	binary.BigEndian.PutUint32(data[4:], p.Y)
+	return data, nil
+}
+
+func (p *testPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return errors.New("a point must be 8 bytes")
+	}
+
+	p.X = binary.BigEndian.Uint32(data)
+	p.Y = binary.BigEndian.Uint32(data[4:])
+	return nil
+}
+
+func TestTypedCodecs(t *testing.T) {
+	product := testProduct{ID: 1, Name: "Widget", Price: 9.99}
+	for name, codec := range map[string]Codec{"json": JSONCodec{}, "gob": GobCodec{}} {
+		typed := NewTyped[testProduct](NewCache(), codec)
+		if r := typed.Set("product:1", product, time.Minute); r.Err != nil {
+			t.Fatalf("Couldn't set a product with %v: %+v", name, r.Err)
+		}
+
+		got, r := typed.Get("product:1")
+		if r.Err != nil || got != product {
+			t.Fatalf("Expected %+v with %v but got %+v %v", product, name, got, r)
+		}
+	}
+
+	points := NewTyped[testPoint](NewCache(), BinaryCodec{})
+	points.Set("point", testPoint{X: 3, Y: 4}, 0)
+	if got, r := points.Get("point"); r.Err != nil || got.X != 3 || got.Y != 4 {
+		t.Fatalf("Expected the point to survive a round trip: %+v %v", got, r)
+	}
+
+	// messages are usually used through pointers
+	for name, codec := range map[string]Codec{"json": JSONCodec{}, "gob": GobCodec{}, "binary": BinaryCodec{}} {
+		pointers := NewTyped[*testPoint](NewCache(), codec)
+		if r := pointers.Set("point", &testPoint{X: 3, Y: 4}, 0); r.Err != nil {
+			t.Fatalf("Couldn't set a pointer with %v: %+v", name, r.Err)
+		}
+
+		if got, r := pointers.Get("point"); r.Err != nil || got == nil || *got != (testPoint{X: 3, Y: 4}) {
+			t.Fatalf("Expected the pointer to survive a round trip with %v: %+v %v", name, got, r)
+		}
+	}
+
+	if r := NewTyped[testProduct](NewCache(), BinaryCodec{}).Set("product", product, 0); r.Action != Failed {
+		t.Fatalf("Expected a value that can't encode itself to fail: %v", r)
+	} else if _, ok := r.Err.(ErrEncode); !ok {
+		t.Fatalf("Expected ErrEncode but got %T", r.Err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	c := NewCache()
+	typed := NewTyped[testProduct](c, JSONCodec{})
+	c.Set("garbage", "not json", 0)
+
+	if _, r := typed.Get("missing"); r.Action != Failed {
+		t.Fatalf("Expected a missing key to fail: %v", r)
+	} else if _, ok := r.Err.(ErrKeyNotFound); !ok {
+		t.Fatalf("Expected ErrKeyNotFound for a missing key but got %T", r.Err)
+	}
+
+	if _, r := typed.Get("garbage"); r.Action != Failed || r.GetKey() != "garbage" {
+		t.Fatalf("Expected a value that can't be decoded to fail: %v", r)
+	} else if _, ok := r.Err.(ErrDecode); !ok {
+		t.Fatalf("Expected ErrDecode but got %T", r.Err)
+	}
+
+	values, results := typed.MGet("garbage", "missing")
+	if len(values) != 2 || results[0].Err.(ErrDecode).Key != "garbage" {
+		t.Fatalf("Expected MGet to decode each key on its own: %v %v", values, results)
+	}
+}
+
+func TestTypedGetOrLoad(t *testing.T) {
+	typed := NewTyped[testProduct](NewCache(), JSONCodec{})
+	loads := 0
+	loader := func(key string) (testProduct, error) {
+		loads++
+		return testProduct{ID: 2, Name: key}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, r := typed.GetOrLoad("product:2", loader, time.Minute)
+		if r.Err != nil || got.Name != "product:2" {
+			t.Fatalf("Expected the loaded product but got %+v %v", got, r)
+		}
+	}
+
+	if loads != 1 {
+		t.Fatalf("Expected the product to be loaded once but it was loaded %v times", loads)
+	}
+}
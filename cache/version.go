@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+//ErrVersionConflict is returned by CompareAndSet and CompareAndDelete when the key's version didn't match the version
+//the caller expected.  Actual is zero when the key doesn't exist.
+type ErrVersionConflict struct {
+	Key      string
+	Expected uint64
+	Actual   uint64
+}
+
+func (e ErrVersionConflict) Error() string {
+	return fmt.Sprintf("Version conflict for key %v: expected version %v but found %v", e.Key, e.Expected, e.Actual)
+}
+
+//checkVersion makes sure the version of key in t is the expected version, where a version of zero means the key must
+//not exist.  It must be called from inside of the table's Atomic.
+func checkVersion(t HashTable, key string, expected uint64) error {
+	var actual uint64
+	if r := t.Get(key); r.Err == nil {
+		actual = r.n.version
+	}
+
+	if actual != expected {
+		return ErrVersionConflict{
+			Key:      key,
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+
+	return nil
+}
+
+//CompareAndSet will set a key only if its current version is version.  A version of zero only sets the key if it doesn't
+//exist.  If the version doesn't match the Result's Action will be Failed with an ErrVersionConflict.
+func (c *memCache) CompareAndSet(key string, version uint64, value string, ttl time.Duration, opts ...SetOption) Result {
+	defer c.observe("compareandset", key, time.Now())
+	return c.compareAndSet(key, version, value, ttl, opts...)
+}
+
+func (c *memCache) compareAndSet(key string, version uint64, value string, ttl time.Duration, opts ...SetOption) Result {
+	ks := c.keyspace()
+	so := newSetOptions(opts)
+	if err := so.validate(ttl); err != nil {
+		return Result{
+			Action: Failed,
+			Err:    err,
+		}
+	}
+
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		if err := checkVersion(t, key, version); err != nil {
+			r = Result{
+				Action: Failed,
+				Err:    err,
+			}
+			return
+		}
+
+		r = ks.set(t, key, value, ttl, so)
+	})
+
+	if r.prev != nil {
+		c.evicted(*r.prev, Replaced)
+	}
+
+	return r
+}
+
+//CompareAndDelete will unset a key only if its current version is version.  If the version doesn't match the Result's
+//Action will be Failed with an ErrVersionConflict.
+func (c *memCache) CompareAndDelete(key string, version uint64) Result {
+	defer c.observe("compareanddelete", key, time.Now())
+	ks := c.keyspace()
+	var r Result
+	ks.table.Atomic(func(t HashTable) {
+		if err := checkVersion(t, key, version); err != nil {
+			r = Result{
+				Action: Failed,
+				Err:    err,
+			}
+			return
+		}
+
+		r = ks.unset(t, key)
+	})
+
+	if r.Action == Deleted {
+		c.evicted(r.n, Removed)
+	}
+
+	return r
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	c := NewCache()
+	r1 := c.Set("Test Key 1", "Test Value 1", 0)
+	r2 := c.Set("Test Key 2", "Test Value 2", 0)
+	r3 := c.Set("Test Key 1", "Test Value 3", 0)
+	if r1.GetVersion() == 0 || r1.GetVersion() >= r2.GetVersion() || r2.GetVersion() >= r3.GetVersion() {
+		t.Fatalf("Expected versions to increase with every set: %v, %v, %v", r1.GetVersion(), r2.GetVersion(), r3.GetVersion())
+	}
+
+	if r := c.Get("Test Key 1"); r.GetVersion() != r3.GetVersion() {
+		t.Fatalf("Get returned version %v, expected %v", r.GetVersion(), r3.GetVersion())
+	}
+
+	// versions shouldn't be reused after a key is unset and set again
+	c.Unset("Test Key 1")
+	if r := c.Set("Test Key 1", "Test Value 1", 0); r.GetVersion() <= r3.GetVersion() {
+		t.Fatalf("Version %v was reused after unsetting key", r.GetVersion())
+	}
+}
+
+func TestCompareAndSet(t *testing.T) {
+	c := NewCache()
+	r := c.CompareAndSet("Test Key 1", 0, "Test Value 1", 0)
+	if r.Action != Created || r.Err != nil {
+		t.Fatalf("Expected CompareAndSet with version 0 to create a missing key: %v", r)
+	}
+
+	conflict := c.CompareAndSet("Test Key 1", 0, "Test Value 2", 0)
+	if e, ok := conflict.Err.(ErrVersionConflict); conflict.Action != Failed || !ok || e.Actual != r.GetVersion() {
+		t.Fatalf("Expected a version conflict setting an existing key with version 0: %v", conflict)
+	}
+
+	updated := c.CompareAndSet("Test Key 1", r.GetVersion(), "Test Value 2", 0)
+	if updated.Action != Updated || updated.Err != nil || updated.GetValue() != "Test Value 2" {
+		t.Fatalf("Expected CompareAndSet with the current version to update the key: %v", updated)
+	}
+
+	if stale := c.CompareAndSet("Test Key 1", r.GetVersion(), "Test Value 3", 0); stale.Action != Failed {
+		t.Fatalf("Expected CompareAndSet with a stale version to fail: %v", stale)
+	}
+
+	if del := c.CompareAndDelete("Test Key 1", r.GetVersion()); del.Action != Failed {
+		t.Fatalf("Expected CompareAndDelete with a stale version to fail: %v", del)
+	}
+
+	if del := c.CompareAndDelete("Test Key 1", updated.GetVersion()); del.Action != Deleted || del.Err != nil {
+		t.Fatalf("Expected CompareAndDelete with the current version to delete the key: %v", del)
+	}
+}
+
+func TestConcurrentCompareAndSet(t *testing.T) {
+	c := NewCache()
+	c.Set("Counter", "", 0)
+
+	// every goroutine does a read-modify-write and retries on conflicts, so no increment should be lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				r := c.Get("Counter")
+				if c.CompareAndSet("Counter", r.GetVersion(), r.GetValue()+"x", 0).Err == nil {
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	if r := c.Get("Counter"); len(r.GetValue()) != 20 {
+		t.Fatalf("Expected 20 increments but got %v", len(r.GetValue()))
+	}
+}